@@ -1,19 +1,20 @@
 package agent
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"evaluator/knovvu"
 	"evaluator/llm"
 	"evaluator/repository"
+	"evaluator/target"
 	"fmt"
 	"log"
 	"strings"
-
-	"github.com/google/uuid"
 )
 
-var ErrInternal = errors.New("failed to send message to Knovvu")
+var ErrInternal = errors.New("failed to send message to the target")
 
 // Agent struct holds the state and configuration for a single scenario execution.
 type Agent struct {
@@ -22,6 +23,17 @@ type Agent struct {
 	State           llm.CurrentState
 	Project         string
 	LLM             llm.LLM
+	LLMOptions      llm.CallOptions // Per-call tuning (temperature, max tokens, seed, model) from the run configuration
+	Judge           llm.LLM         // Produces the final verdict; nil means LLM judges its own conversation
+	JudgeOptions    llm.CallOptions
+	JudgePanel      *llm.JudgePanel // When set, replaces Judge with a multi-judge consensus
+	Target          target.Target   // Bot under test; nil uses the default Knovvu configuration
+	StartEvent      string          // Sent before the first turn to trigger the VA greeting; empty lets the simulator speak first
+	SimulatorPrompt string          // System prompt of the simulator; empty uses llm.SystemPrompt
+	JudgePrompt     string          // Prompt of the judge; empty uses llm.JudgePrompt
+	PromptVersion   string          // Sent to the simulator as LLMInput.Version; empty uses the built-in version
+	Persona         *llm.Persona    // User the simulator plays; nil plays the generic tester
+	Language        string          // Code of the language to converse in, e.g. "tr"; empty leaves it to the prompt
 	DB              *sql.DB
 	Store           repository.Store
 }
@@ -39,56 +51,204 @@ func NewAgent(project, scenario, expectedOutcome string, initialState llm.Curren
 	}
 }
 
-// Helper function to extract quick replies from Knovvu hero card attachments
-func extractQuickRepliesFromAttachments(attachments []interface{}) string {
-	for _, att := range attachments {
-		attMap, ok := att.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		if attMap["contentType"] == "application/vnd.microsoft.card.hero" {
-			content, ok := attMap["content"].(map[string]interface{})
-			if !ok {
-				continue
-			}
-			promptText, _ := content["text"].(string)
-			buttons, ok := content["buttons"].([]interface{})
-			if !ok || len(buttons) == 0 {
-				continue
-			}
-			var quickReplies []string
-			for _, btn := range buttons {
-				btnMap, ok := btn.(map[string]interface{})
-				if !ok {
-					continue
-				}
-				title, _ := btnMap["title"].(string)
-				value, _ := btnMap["value"].(string)
-				quickReplies = append(quickReplies, "  - "+title+" (value: "+value+")")
-			}
-			result := ""
-			if promptText != "" {
-				result += "Prompt: " + promptText + "\n"
+// renderCards renders the cards of an activity with their buttons. Carousels number their cards.
+func renderCards(cards []target.Card, layout string) string {
+	var parts []string
+	for i, card := range cards {
+		var lines []string
+		if len(cards) > 1 && layout == target.CarouselLayout {
+			lines = append(lines, fmt.Sprintf("Card %d of %d:", i+1, len(cards)))
+		}
+		if card.Title != "" {
+			lines = append(lines, "Title: "+card.Title)
+		}
+		if card.Subtitle != "" {
+			lines = append(lines, "Subtitle: "+card.Subtitle)
+		}
+		if card.Text != "" {
+			lines = append(lines, "Prompt: "+card.Text)
+		}
+		if len(card.Buttons) > 0 {
+			lines = append(lines, renderOptions(card.Buttons))
+		}
+		parts = append(parts, strings.Join(lines, "\n"))
+	}
+	if len(parts) > 1 && layout == target.CarouselLayout {
+		return "Carousel:\n" + strings.Join(parts, "\n")
+	}
+	return strings.Join(parts, "\n")
+}
+
+// renderOptions lists clickable actions, showing a button's value when it differs from its title.
+func renderOptions(actions []target.CardAction) string {
+	options := make([]string, len(actions))
+	for i, action := range actions {
+		options[i] = "  - " + action.Title
+		if value := action.ValueString(); value != "" && value != action.Title {
+			options[i] += " (value: " + value + ")"
+		}
+	}
+	return "Options:\n" + strings.Join(options, "\n")
+}
+
+// renderReply turns a target's reply into the messages the simulator and the judge see,
+// one per message activity.
+func renderReply(reply *target.Reply) []string {
+	if reply == nil {
+		return nil
+	}
+	var messages []string
+	for _, a := range reply.Messages() {
+		if text := renderActivity(a); text != "" {
+			messages = append(messages, text)
+		}
+	}
+	return messages
+}
+
+// replyLanguage detects the language of a reply from the text the VA wrote, leaving out the
+// labels renderReply adds around cards and options.
+func replyLanguage(reply *target.Reply) string {
+	if reply == nil {
+		return ""
+	}
+	var texts []string
+	for _, a := range reply.Messages() {
+		texts = append(texts, a.Text)
+		for _, card := range a.Cards {
+			texts = append(texts, card.Title, card.Subtitle, card.Text)
+		}
+		for _, action := range a.Actions() {
+			texts = append(texts, action.Title)
+		}
+	}
+	return llm.DetectLanguage(strings.Join(texts, "\n"))
+}
+
+// renderActivity renders a message activity: its text, its cards, then the suggested actions
+// and quick replies.
+func renderActivity(a target.ReplyActivity) string {
+	var parts []string
+	if a.Text != "" {
+		parts = append(parts, a.Text)
+	}
+	if cards := renderCards(a.Cards, a.AttachmentLayout); cards != "" {
+		parts = append(parts, cards)
+	}
+	options := a.SuggestedActions
+	for _, q := range a.QuickReplies {
+		options = append(options, target.CardAction{Type: target.ActionIMBack, Title: q, Value: q})
+	}
+	if len(options) > 0 {
+		parts = append(parts, renderOptions(options))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// send delivers the simulator's turn. An explicit select_option clicks the named option of
+// the previous reply; a typed message that is exactly the title or value of an option clicks
+// it as well. Targets that can click buttons receive the postBack a real channel would send;
+// others receive the option's text.
+func (a *Agent) send(ctx context.Context, conversationID string, out *llm.LLMOutput, message string, previous *target.Reply) (*target.Reply, error) {
+	choice, selected := message, out.Action == llm.ActionSelectOption && out.SelectedOption != ""
+	if selected {
+		choice = out.SelectedOption
+	}
+	action, found := previous.FindAction(choice)
+	if selected && !found {
+		log.Printf("Selected option %q is not offered by the VA; sending it as text", choice)
+	}
+	if found && action.Type != target.ActionOpenURL {
+		if sender, ok := a.Target.(target.ActionSender); ok {
+			log.Printf("Clicking %s button %q", action.Type, action.Title)
+			return sender.SendAction(ctx, conversationID, action)
+		}
+		if selected {
+			text, _, _ := action.Payload()
+			if text == "" {
+				text = action.Title
 			}
-			result += "Options:\n" + strings.Join(quickReplies, "\n")
-			return result
+			return a.Target.SendMessage(ctx, conversationID, text)
 		}
 	}
-	return ""
+	return a.Target.SendMessage(ctx, conversationID, choice)
+}
+
+// greet sends the start event and records the VA's greeting as turn 0 of the history.
+// Targets that can't greet are logged and skipped.
+func (a *Agent) greet(ctx context.Context, conversationID string) (*target.Reply, error) {
+	greeter, ok := a.Target.(target.Greeter)
+	if !ok {
+		log.Printf("Target does not support start events; skipping %q", a.StartEvent)
+		return nil, nil
+	}
+	reply, err := greeter.Greet(ctx, conversationID, a.StartEvent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send start event %q: %w", a.StartEvent, err)
+	}
+	messages := renderReply(reply)
+	greeting := strings.Join(messages, "\n")
+	fmt.Printf("Received greeting from VA: %s\n", greeting)
+	item := llm.HistoryItem{Turn: 0, Assistant: greeting, VALanguage: replyLanguage(reply)}
+	if len(messages) > 1 {
+		item.AssistantMessages = messages
+	}
+	item.Activities, _ = json.Marshal(reply.Activities)
+	a.State.History = append(a.State.History, item)
+	return reply, nil
 }
 
 // Run executes the agent's main loop until the scenario is fulfilled or max turns are reached.
 // If an error occurs, it is returned and should be handled by the caller (never causes server exit).
-func (a *Agent) Run() (*llm.CurrentState, *llm.JudgmentResult, error) {
+// If ctx is cancelled, Run stops before the next LLM or VA call and returns the partial state
+// together with the context error; no judgment is produced in that case.
+func (a *Agent) Run(ctx context.Context) (*llm.CurrentState, *llm.JudgmentResult, error) {
 	fmt.Printf("--- Starting Scenario: %s ---\n", a.Scenario)
 
-	knovvuToken, err := knovvu.GetKnovvuToken()
+	if a.Target == nil {
+		a.Target = target.NewKnovvu(knovvu.NewClient(knovvu.Config{}), a.Project)
+	}
+	conversationID, err := a.Target.StartConversation(ctx)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get Knovvu token: %w", err)
+		if ctx.Err() != nil {
+			return &a.State, nil, ctx.Err()
+		}
+		return nil, nil, fmt.Errorf("failed to start conversation: %w", err)
 	}
-	conversationID := uuid.New().String()
+	defer func() {
+		// Ending must still happen when the run was cancelled
+		if err := a.Target.EndConversation(context.WithoutCancel(ctx), conversationID); err != nil {
+			log.Printf("failed to end conversation %s: %v", conversationID, err)
+		}
+	}()
 
+	systemPrompt, judgePrompt, version := a.SimulatorPrompt, a.JudgePrompt, a.PromptVersion
+	if systemPrompt == "" {
+		systemPrompt = llm.SystemPrompt
+	}
+	if judgePrompt == "" {
+		judgePrompt = llm.JudgePrompt
+	}
+	if version == "" {
+		version = llm.BuiltinPromptVersion
+	}
+	persona := a.Persona.WithGuidelines()
+	language := llm.SimulatorLanguage(a.Language)
+
+	var lastReply *target.Reply
+	if a.StartEvent != "" {
+		if lastReply, err = a.greet(ctx, conversationID); err != nil {
+			if ctx.Err() != nil {
+				return &a.State, nil, ctx.Err()
+			}
+			return nil, nil, err
+		}
+	}
 	for a.State.TurnCount < a.State.MaxTurns && !a.State.Fulfilled {
+		if ctx.Err() != nil {
+			fmt.Println("\n--- Scenario Cancelled ---")
+			return &a.State, nil, ctx.Err()
+		}
 		a.State.TurnCount++
 		fmt.Printf("\n--- Turn %d ---\n", a.State.TurnCount)
 
@@ -97,11 +257,16 @@ func (a *Agent) Run() (*llm.CurrentState, *llm.JudgmentResult, error) {
 			Scenario:        a.Scenario,
 			ExpectedOutcome: a.ExpectedOutcome,
 			CurrentState:    a.State,
-			Version:         "2.0",
+			Version:         version,
+			Persona:         persona,
+			Language:        language,
 		}
 
-		llmResponse, err := a.LLM.GenerateContentREST(llm.SystemPrompt, llmInput)
+		llmResponse, err := a.LLM.GenerateContentREST(ctx, systemPrompt, llmInput, a.LLMOptions)
 		if err != nil {
+			if ctx.Err() != nil {
+				return &a.State, nil, ctx.Err()
+			}
 			return nil, nil, fmt.Errorf("failed to generate content from LLM: %w", err)
 		}
 
@@ -113,35 +278,45 @@ func (a *Agent) Run() (*llm.CurrentState, *llm.JudgmentResult, error) {
 		log.Printf("LLM Strategy: %s\n", llmResponse.Strategy)
 		log.Printf("Is Fullfilled: %v\\n", a.State.Fulfilled)
 
-		// 2. Send the message to Knovvu VA
+		// 2. Send the message to the VA
 		userMessage := llmResponse.NextMessage
+		if userMessage == "" && llmResponse.Action == llm.ActionSelectOption {
+			userMessage = llmResponse.SelectedOption
+		}
 		fmt.Printf("Sending to VA: %s\n", userMessage)
 		if userMessage != "" {
 
-			_, knovvuResp, err := knovvu.SendKnovvuMessage(a.Project, knovvuToken, userMessage, conversationID)
+			reply, err := a.send(ctx, conversationID, llmResponse, userMessage, lastReply)
 			if err != nil {
-				fmt.Printf("failed to send message to Knovvu: %v", err)
+				if ctx.Err() != nil {
+					return &a.State, nil, ctx.Err()
+				}
+				fmt.Printf("failed to send message to the target: %v", err)
 				return nil, nil, ErrInternal
 			}
 
-			vaResponse := "No response text found."
-			if knovvuResp != nil {
-				if knovvuResp.Text != "" {
-					vaResponse = knovvuResp.Text
-				} else if len(knovvuResp.Attachments) > 0 {
-					quickReplies := extractQuickRepliesFromAttachments(knovvuResp.Attachments)
-					if quickReplies != "" {
-						vaResponse = quickReplies
-					}
-				}
+			lastReply = reply
+			messages := renderReply(reply)
+			vaResponse := strings.Join(messages, "\n")
+			if vaResponse == "" {
+				vaResponse = "No response text found."
 			}
 			fmt.Printf("Received from VA: %s\n", vaResponse)
 			// 3. Update the history
-			a.State.History = append(a.State.History, llm.HistoryItem{
-				Turn:      a.State.TurnCount,
-				User:      userMessage,
-				Assistant: vaResponse,
-			})
+			item := llm.HistoryItem{
+				Turn:       a.State.TurnCount,
+				User:       userMessage,
+				Assistant:  vaResponse,
+				VALanguage: replyLanguage(reply),
+				Simulator:  llmResponse,
+			}
+			if len(messages) > 1 {
+				item.AssistantMessages = messages
+			}
+			if reply != nil {
+				item.Activities, _ = json.Marshal(reply.Activities)
+			}
+			a.State.History = append(a.State.History, item)
 
 		}
 
@@ -151,9 +326,21 @@ func (a *Agent) Run() (*llm.CurrentState, *llm.JudgmentResult, error) {
 			break
 		}
 	}
-	judgeInput := llm.JudgeInput{Scenario: a.Scenario, Conversation: a.State.History}
-	judgeReslts, err := a.LLM.GenerateJudgmentREST(llm.JudgePrompt, judgeInput)
+	judgeInput := llm.JudgeInput{Scenario: a.Scenario, Conversation: a.State.History, Persona: persona, Language: llm.JudgeLanguage(a.Language)}
+	judge, judgeOptions := a.LLM, a.LLMOptions
+	if a.Judge != nil {
+		judge, judgeOptions = a.Judge, a.JudgeOptions
+	}
+	var judgeReslts *llm.JudgmentResult
+	if a.JudgePanel != nil {
+		judgeReslts, err = a.JudgePanel.Judge(ctx, judgePrompt, judgeInput)
+	} else {
+		judgeReslts, err = judge.GenerateJudgmentREST(ctx, judgePrompt, judgeInput, judgeOptions)
+	}
 	if err != nil {
+		if ctx.Err() != nil {
+			return &a.State, nil, ctx.Err()
+		}
 		return nil, nil, fmt.Errorf("failed to generate Judgement Results from LLM: %w", err)
 	}
 
@@ -161,6 +348,9 @@ func (a *Agent) Run() (*llm.CurrentState, *llm.JudgmentResult, error) {
 	fmt.Printf("Confidence is %s\n", judgeReslts.Confidence)
 	fmt.Printf("Scenario Completion Score is %v\n", judgeReslts.ScenarioCompletionScore)
 	fmt.Printf("Conversation Quality Score is %v\n", judgeReslts.ConversationQualityScore)
+	if a.JudgePanel != nil {
+		fmt.Printf("Judge Agreement Rate is %.2f\n", judgeReslts.AgreementRate)
+	}
 	fmt.Printf("Evidence Summary %v\n\n", judgeReslts.EvidenceSummary)
 	if !a.State.Fulfilled {
 		fmt.Println("\n--- Max turns reached ---")
@@ -170,7 +360,8 @@ func (a *Agent) Run() (*llm.CurrentState, *llm.JudgmentResult, error) {
 }
 
 // ParallelRun runs up to 5 scenarios in parallel at a time, each with its expected outcome.
-func (a *Agent) ParallelRun(scenarios []string, expectedOutcomes []string) ([]*llm.CurrentState, []error) {
+// Cancelling ctx stops every scenario that is still running.
+func (a *Agent) ParallelRun(ctx context.Context, scenarios []string, expectedOutcomes []string) ([]*llm.CurrentState, []error) {
 	if len(scenarios) != len(expectedOutcomes) {
 		return nil, []error{fmt.Errorf("scenarios and expectedOutcomes must have the same length")}
 	}
@@ -196,8 +387,17 @@ func (a *Agent) ParallelRun(scenarios []string, expectedOutcomes []string) ([]*l
 				Fulfilled: false,
 			}
 			subAgent := NewAgent(a.Project, scenarios[idx], expectedOutcomes[idx], initState, a.LLM, a.DB)
+			subAgent.LLMOptions = a.LLMOptions
+			subAgent.Judge, subAgent.JudgeOptions = a.Judge, a.JudgeOptions
+			subAgent.JudgePanel = a.JudgePanel
+			subAgent.Target = a.Target
+			subAgent.StartEvent = a.StartEvent
+			subAgent.SimulatorPrompt, subAgent.JudgePrompt = a.SimulatorPrompt, a.JudgePrompt
+			subAgent.PromptVersion = a.PromptVersion
+			subAgent.Persona = a.Persona
+			subAgent.Language = a.Language
 			//TODO: add judgment result to the state
-			state, _, err := subAgent.Run()
+			state, _, err := subAgent.Run(ctx)
 			resCh <- result{idx: idx, state: state, err: err}
 		}
 	}
@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"evaluator/llm"
+	"evaluator/target"
+	"reflect"
+	"testing"
+)
+
+const (
+	greeting    = "Hello! I'm the demo assistant. How can I help you today?\nOptions:\n  - Check my balance\n  - Talk to an agent"
+	balance     = "Your balance is $1,250.00. Is there anything else I can help with?"
+	goodbye     = "You're welcome. Goodbye!"
+	notFollowed = `Sorry, I didn't understand "Can you give me more details?". You can ask about your balance or talk to an agent.`
+)
+
+// newMockAgent wires the mock LLM to the mock target, as a run in offline mode does.
+func newMockAgent(t *testing.T, client *llm.MockClient, cfg target.MockConfig, maxTurns int16) *Agent {
+	t.Helper()
+	bot, err := target.NewMock(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAgent("demo", "Check my balance", "The VA reports the balance", llm.CurrentState{History: []llm.HistoryItem{}, MaxTurns: maxTurns}, client, nil)
+	a.Target = bot
+	return a
+}
+
+func assistantReplies(state *llm.CurrentState) []string {
+	var replies []string
+	for _, h := range state.History {
+		replies = append(replies, h.Assistant)
+	}
+	return replies
+}
+
+func TestRunBuiltinScript(t *testing.T) {
+	client, err := llm.NewMockClient("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newMockAgent(t, client, target.MockConfig{}, 10)
+	state, judgment, err := a.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The third turn fulfils the scenario, so the loop ends well before MaxTurns
+	if !state.Fulfilled || state.TurnCount != 3 {
+		t.Errorf("Fulfilled = %t after %d turns, want true after 3", state.Fulfilled, state.TurnCount)
+	}
+	if want := []string{greeting, notFollowed, goodbye}; !reflect.DeepEqual(assistantReplies(state), want) {
+		t.Errorf("VA replies = %q, want %q", assistantReplies(state), want)
+	}
+	if first := state.History[0]; first.User != "Hello, I need help with the following: Check my balance" || first.Simulator == nil {
+		t.Errorf("turn 1 = %+v, want the scenario and the simulator output", first)
+	}
+	if judgment == nil || judgment.Judgement != llm.JudgementPass || judgment.EvidenceSummary != "Mock judgment of a 3-turn conversation." {
+		t.Errorf("judgment = %+v", judgment)
+	}
+}
+
+func TestRunScriptedOptions(t *testing.T) {
+	client := &llm.MockClient{Script: llm.MockScript{
+		Outputs: []llm.LLMOutput{
+			{NextMessage: "hi"},
+			// Selecting an option without text sends the option, even to targets without buttons
+			{Action: llm.ActionSelectOption, SelectedOption: "Check my balance"},
+			{NextMessage: "thanks"},
+		},
+		Judgment: &llm.JudgmentResult{Judgement: llm.JudgementFail, EvidenceSummary: "scripted"},
+	}}
+	a := newMockAgent(t, client, target.MockConfig{}, 2)
+	state, judgment, err := a.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// MaxTurns stops the script early and the run is still judged
+	if state.Fulfilled || state.TurnCount != 2 {
+		t.Errorf("Fulfilled = %t after %d turns, want false after 2", state.Fulfilled, state.TurnCount)
+	}
+	if got := state.History[1]; got.User != "Check my balance" || got.Assistant != balance {
+		t.Errorf("turn 2 = %q -> %q, want the selected option and the balance", got.User, got.Assistant)
+	}
+	if judgment == nil || judgment.Judgement != llm.JudgementFail || judgment.EvidenceSummary != "scripted" {
+		t.Errorf("judgment = %+v, want the scripted one", judgment)
+	}
+
+	// Once the script runs out the conversation ends on the next turn
+	a = newMockAgent(t, &llm.MockClient{Script: llm.MockScript{Outputs: []llm.LLMOutput{{NextMessage: "hi"}}}}, target.MockConfig{}, 10)
+	if state, _, err = a.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !state.Fulfilled || state.TurnCount != 2 || len(state.History) != 1 {
+		t.Errorf("exhausted script: Fulfilled = %t, %d turns, %d history items", state.Fulfilled, state.TurnCount, len(state.History))
+	}
+}
+
+func TestRunStatefulMockTarget(t *testing.T) {
+	cfg := target.MockConfig{
+		Greeting: &target.MockRule{Reply: "Which account?", QuickReplies: []string{"Checking"}},
+		Rules: []target.MockRule{
+			{Pattern: "(?i)checking", Reply: "PIN please", NextState: "pin"},
+			{Pattern: `^\d{4}$`, State: "pin", Reply: "Verified", NextState: "done"},
+		},
+		Fallback: "Say again: {{message}}",
+	}
+	client := &llm.MockClient{Script: llm.MockScript{Outputs: []llm.LLMOutput{
+		{NextMessage: "1234"}, // no state yet, so the PIN rule doesn't match
+		{NextMessage: "Checking"},
+		{NextMessage: "1234", Fulfilled: true},
+	}}}
+	a := newMockAgent(t, client, cfg, 10)
+	a.StartEvent = "conversationUpdate"
+	state, _, err := a.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The greeting is turn 0 of the history
+	want := []string{"Which account?\nOptions:\n  - Checking", "Say again: 1234", "PIN please", "Verified"}
+	if !reflect.DeepEqual(assistantReplies(state), want) {
+		t.Errorf("VA replies = %q, want %q", assistantReplies(state), want)
+	}
+	if state.History[0].Turn != 0 || state.History[0].User != "" {
+		t.Errorf("greeting = %+v, want turn 0 without a user message", state.History[0])
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	client, err := llm.NewMockClient(llm.MockModel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newMockAgent(t, client, target.MockConfig{}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	state, judgment, err := a.Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() error = %v, want context.Canceled", err)
+	}
+	if state == nil || len(state.History) != 0 || judgment != nil {
+		t.Errorf("Run() = %+v, %+v, want the empty partial state and no judgment", state, judgment)
+	}
+}
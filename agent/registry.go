@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"context"
+	"sync"
+)
+
+// activeRun tracks a single in-flight run request, the runs rows it has created so far and
+// the function that stops it. Project-wide requests also track the scenario they are
+// executing, which a scenario stop request cancels on its own.
+type activeRun struct {
+	ProjectID  int
+	ScenarioID int
+	RunIDs     []int
+	cancel     context.CancelFunc
+
+	runScenarios  map[int]int // Scenario of each run
+	current       int         // Scenario being executed by a project-wide request; 0 between scenarios
+	cancelCurrent context.CancelFunc
+}
+
+// RunRegistry keeps track of in-process run requests so they can be cancelled from HTTP
+// handlers while the agent goroutine is still executing. A request is registered before its
+// goroutine starts, so a stop request can't miss it, and its runs rows are added as they are
+// created.
+type RunRegistry struct {
+	mu   sync.Mutex
+	next int
+	runs map[int]*activeRun
+}
+
+// NewRunRegistry creates an empty run registry.
+func NewRunRegistry() *RunRegistry {
+	return &RunRegistry{runs: make(map[int]*activeRun)}
+}
+
+// Register records a run request and returns the handle to add its runs to and to unregister
+// it with. scenarioID is 0 for project-wide requests.
+func (r *RunRegistry) Register(projectID, scenarioID int, cancel context.CancelFunc) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.next++
+	r.runs[r.next] = &activeRun{ProjectID: projectID, ScenarioID: scenarioID, cancel: cancel, runScenarios: make(map[int]int)}
+	return r.next
+}
+
+// AddRun records a runs row created by a registered request for scenarioID. It is ignored
+// once the request has been unregistered.
+func (r *RunRegistry) AddRun(handle, scenarioID, runID int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if run, ok := r.runs[handle]; ok {
+		run.RunIDs = append(run.RunIDs, runID)
+		run.runScenarios[runID] = scenarioID
+	}
+}
+
+// StartScenario records that a project-wide request is now executing scenarioID. A stop
+// request for the scenario calls cancel, which should end the scenario's runs but not the
+// rest of the request.
+func (r *RunRegistry) StartScenario(handle, scenarioID int, cancel context.CancelFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if run, ok := r.runs[handle]; ok {
+		run.current, run.cancelCurrent = scenarioID, cancel
+	}
+}
+
+// EndScenario records that a project-wide request has finished its current scenario.
+func (r *RunRegistry) EndScenario(handle int) {
+	r.StartScenario(handle, 0, nil)
+}
+
+// Unregister removes a request once it has finished. It is safe to call for unknown handles.
+func (r *RunRegistry) Unregister(handle int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.runs, handle)
+}
+
+// IsActive reports whether the run belongs to a registered request.
+func (r *RunRegistry) IsActive(runID int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.find(runID) != nil
+}
+
+// Cancel stops the request a run belongs to. It returns false if the run is not active.
+func (r *RunRegistry) Cancel(runID int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run := r.find(runID)
+	if run == nil {
+		return false
+	}
+	run.cancel()
+	return true
+}
+
+func (r *RunRegistry) find(runID int) *activeRun {
+	for _, run := range r.runs {
+		for _, id := range run.RunIDs {
+			if id == runID {
+				return run
+			}
+		}
+	}
+	return nil
+}
+
+// CancelScenario stops every active request started for the given scenario, and the scenario
+// in project-wide requests that are executing it. It returns the IDs of the scenario's runs so
+// far and whether anything was cancelled.
+func (r *RunRegistry) CancelScenario(scenarioID int) ([]int, bool) {
+	cancelled := []int{}
+	if scenarioID == 0 { // the scenario of project-wide requests
+		return cancelled, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	found := false
+	for _, run := range r.runs {
+		switch {
+		case run.ScenarioID == scenarioID:
+			run.cancel()
+		case run.current == scenarioID && run.cancelCurrent != nil:
+			run.cancelCurrent()
+		default:
+			continue
+		}
+		found = true
+		for _, id := range run.RunIDs {
+			if run.runScenarios[id] == scenarioID {
+				cancelled = append(cancelled, id)
+			}
+		}
+	}
+	return cancelled, found
+}
+
+// CancelProject stops every active request belonging to the given project. It returns the IDs
+// of their runs so far and whether any request was active.
+func (r *RunRegistry) CancelProject(projectID int) ([]int, bool) {
+	return r.cancelWhere(func(run *activeRun) bool { return run.ProjectID == projectID })
+}
+
+func (r *RunRegistry) cancelWhere(match func(*activeRun) bool) ([]int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cancelled := []int{}
+	found := false
+	for _, run := range r.runs {
+		if match(run) {
+			run.cancel()
+			cancelled = append(cancelled, run.RunIDs...)
+			found = true
+		}
+	}
+	return cancelled, found
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestRegistryCancelScenario(t *testing.T) {
+	r := NewRunRegistry()
+
+	// A single-scenario request for scenario 1
+	single, cancelSingle := context.WithCancel(context.Background())
+	defer cancelSingle()
+	hSingle := r.Register(10, 1, cancelSingle)
+	r.AddRun(hSingle, 1, 100)
+
+	// A project run of project 10 that created runs for scenarios 1 and 2 up front and is
+	// executing scenario 2
+	project, cancelProject := context.WithCancel(context.Background())
+	defer cancelProject()
+	hProject := r.Register(10, 0, cancelProject)
+	r.AddRun(hProject, 1, 200)
+	r.AddRun(hProject, 2, 201)
+	r.AddRun(hProject, 2, 202)
+	current, cancelCurrent := context.WithCancel(project)
+	r.StartScenario(hProject, 2, cancelCurrent)
+
+	// Scenario 0 stands for project-wide requests, not a scenario
+	if ids, found := r.CancelScenario(0); found || len(ids) != 0 {
+		t.Fatalf("CancelScenario(0) = %v, %t", ids, found)
+	}
+	// Scenario 1 only runs on its own right now; the project run is on scenario 2
+	ids, found := r.CancelScenario(1)
+	if !found || !reflect.DeepEqual(ids, []int{100}) {
+		t.Errorf("CancelScenario(1) = %v, %t, want [100]", ids, found)
+	}
+	if single.Err() == nil || project.Err() != nil || current.Err() != nil {
+		t.Errorf("CancelScenario(1) cancelled the wrong requests")
+	}
+
+	// Stopping the project run's current scenario leaves the rest of the project run going
+	ids, found = r.CancelScenario(2)
+	if !found || !reflect.DeepEqual(ids, []int{201, 202}) {
+		t.Errorf("CancelScenario(2) = %v, %t, want [201 202]", ids, found)
+	}
+	if current.Err() == nil || project.Err() != nil {
+		t.Errorf("CancelScenario(2) didn't cancel only the current scenario")
+	}
+
+	r.EndScenario(hProject)
+	if _, found := r.CancelScenario(2); found {
+		t.Errorf("CancelScenario(2) matched a scenario that has finished")
+	}
+
+	ids, found = r.CancelProject(10)
+	sort.Ints(ids)
+	if !found || !reflect.DeepEqual(ids, []int{100, 200, 201, 202}) || project.Err() == nil {
+		t.Errorf("CancelProject(10) = %v, %t", ids, found)
+	}
+
+	r.Unregister(hProject)
+	r.AddRun(hProject, 1, 300) // ignored once unregistered
+	if r.IsActive(200) || r.IsActive(300) || !r.IsActive(100) {
+		t.Errorf("IsActive() after Unregister is wrong")
+	}
+	if r.Cancel(200) || !r.Cancel(100) {
+		t.Errorf("Cancel() matched the wrong runs")
+	}
+}
@@ -0,0 +1,288 @@
+// Package cassette records outgoing HTTP traffic (LLM providers, Knovvu and other targets)
+// to JSON files and replays it later without touching the network.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Mode selects whether a cassette captures or serves traffic.
+type Mode string
+
+const (
+	ModeRecord Mode = "record"
+	ModeReplay Mode = "replay"
+)
+
+// ParseMode validates a mode name. An empty name selects ModeReplay.
+func ParseMode(name string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(name))); m {
+	case "":
+		return ModeReplay, nil
+	case ModeRecord, ModeReplay:
+		return m, nil
+	}
+	return "", fmt.Errorf("invalid cassette mode: %s", name)
+}
+
+// redacted replaces secrets before they are written to a cassette.
+const redacted = "REDACTED"
+
+// secretParams are query and form parameters that never reach a cassette file.
+var secretParams = []string{"key", "api_key", "client_secret"}
+
+// secretFields are JSON response fields that never reach a cassette file, e.g. the tokens of
+// an identity server or Direct Line.
+var secretFields = []string{"access_token", "token", "refresh_token", "id_token"}
+
+// Interaction is one recorded request/response pair. Request headers are not stored and
+// tokens in responses are redacted, so API keys and bearer tokens stay out of cassette files.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type Response struct {
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+}
+
+// Cassette is a file of recorded interactions. It is safe for concurrent use.
+type Cassette struct {
+	Path string
+	Mode Mode
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// Open prepares the cassette at path. In replay mode the file must exist; in record mode
+// it is created (or truncated) on the first recorded interaction.
+func Open(path string, mode Mode) (*Cassette, error) {
+	c := &Cassette{Path: path, Mode: mode}
+	if mode == ModeRecord {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.interactions); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	c.used = make([]bool, len(c.interactions))
+	return c, nil
+}
+
+// Len returns the number of interactions in the cassette.
+func (c *Cassette) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.interactions)
+}
+
+// roundTrip records the request through next, or serves it from the cassette.
+func (c *Cassette) roundTrip(req *http.Request, next http.RoundTripper) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := Request{Method: req.Method, URL: redactURL(req.URL), Body: redactBody(req, body)}
+
+	if c.Mode == ModeReplay {
+		i, ok := c.match(recorded)
+		if !ok {
+			return nil, fmt.Errorf("cassette %s: no recorded response for %s %s", c.Path, recorded.Method, recorded.URL)
+		}
+		return c.interactions[i].Response.httpResponse(req), nil
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, Interaction{
+		Request:  recorded,
+		Response: Response{StatusCode: resp.StatusCode, ContentType: resp.Header.Get("Content-Type"), Body: redactResponse(respBody)},
+	})
+	if err := c.save(); err != nil {
+		return nil, fmt.Errorf("cassette %s: %w", c.Path, err)
+	}
+	return resp, nil
+}
+
+// match returns the first unused interaction with the same method, URL and body. Bodies
+// that embed generated IDs never repeat, so it falls back to the first unused interaction
+// with the same method and URL, which replays calls to an endpoint in recorded order.
+func (c *Cassette) match(r Request) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fallback := -1
+	for i, in := range c.interactions {
+		if c.used[i] || in.Request.Method != r.Method || in.Request.URL != r.URL {
+			continue
+		}
+		if in.Request.Body == r.Body {
+			c.used[i] = true
+			return i, true
+		}
+		if fallback < 0 {
+			fallback = i
+		}
+	}
+	if fallback < 0 {
+		return 0, false
+	}
+	c.used[fallback] = true
+	return fallback, true
+}
+
+// save writes the cassette atomically; the caller holds c.mu.
+func (c *Cassette) save() error {
+	data, err := json.MarshalIndent(c.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0o755); err != nil {
+		return err
+	}
+	tmp := c.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.Path)
+}
+
+func (r Response) httpResponse(req *http.Request) *http.Response {
+	header := make(http.Header)
+	if r.ContentType != "" {
+		header.Set("Content-Type", r.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// readBody reads the request body and restores it for the real transport.
+func readBody(req *http.Request) (string, error) {
+	if req.Body == nil {
+		return "", nil
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("cassette: failed to read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return string(data), nil
+}
+
+func redactURL(u *url.URL) string {
+	copied := *u
+	q := copied.Query()
+	for _, p := range secretParams {
+		if q.Has(p) {
+			q.Set(p, redacted)
+		}
+	}
+	copied.RawQuery = q.Encode()
+	return copied.String()
+}
+
+func redactBody(req *http.Request, body string) string {
+	if !strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		return body
+	}
+	form, err := url.ParseQuery(body)
+	if err != nil {
+		return body
+	}
+	for _, p := range secretParams {
+		if form.Has(p) {
+			form.Set(p, redacted)
+		}
+	}
+	return form.Encode()
+}
+
+// redactResponse replaces the secret fields of a JSON response body, at any depth. Other
+// bodies, and JSON bodies without secrets, are recorded unchanged.
+func redactResponse(body []byte) string {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || !redactFields(v) {
+		return string(body)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return string(body)
+	}
+	return string(data)
+}
+
+// redactFields redacts the secret fields found in v and reports whether there were any.
+func redactFields(v any) bool {
+	found := false
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			if _, ok := field.(string); ok && isSecretField(k) {
+				v[k] = redacted
+				found = true
+			} else if redactFields(field) {
+				found = true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if redactFields(item) {
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+func isSecretField(name string) bool {
+	for _, f := range secretFields {
+		if strings.EqualFold(name, f) {
+			return true
+		}
+	}
+	return false
+}
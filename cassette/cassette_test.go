@@ -0,0 +1,162 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// offline fails every request, so replays prove they never reach the network.
+type offline struct{}
+
+func (offline) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("network access in replay")
+}
+
+func do(t *testing.T, client *http.Client, ctx context.Context, method, rawURL, contentType, body string) (int, string, error) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data), nil
+}
+
+func TestRecordRedactsSecrets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"access_token": "live-access", "expires_in": 3600, "nested": [{"Token": "live-nested", "token": 7}]}`)
+		case "/plain":
+			io.WriteString(w, "token=live-plain")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "sub", "run.json") // directories are created on the first save
+	c, err := Open(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &Transport{Base: http.DefaultTransport, Default: c}}
+	ctx := context.Background()
+
+	// The caller still gets the live response; only the cassette is redacted
+	_, body, err := do(t, client, ctx, http.MethodPost, srv.URL+"/token?key=live-key&lang=en", "application/x-www-form-urlencoded", "grant_type=client_credentials&client_secret=live-secret")
+	if err != nil || !strings.Contains(body, "live-access") {
+		t.Fatalf("recorded request = %q, %v, want the live response", body, err)
+	}
+	// JSON request bodies aren't forms and are stored as sent; non-JSON responses as received
+	do(t, client, ctx, http.MethodPost, srv.URL+"/plain", "application/json", `{"key": "visible"}`)
+	do(t, client, ctx, http.MethodGet, srv.URL+"/missing", "", "")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := string(data)
+	for _, secret := range []string{"live-key", "live-secret", "live-access", "live-nested"} {
+		if strings.Contains(file, secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, file)
+		}
+	}
+	for _, kept := range []string{"lang=en", "grant_type=client_credentials", `\"token\":7`, `\"key\": \"visible\"`, "token=live-plain", `"status_code": 404`} {
+		if !strings.Contains(file, kept) {
+			t.Errorf("cassette lacks %s:\n%s", kept, file)
+		}
+	}
+	if c.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", c.Len())
+	}
+}
+
+func TestReplay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if string(body) == "fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+		io.WriteString(w, `{"echo": "`+string(body)+`"}`)
+	}))
+	path := filepath.Join(t.TempDir(), "run.json")
+	rec, err := Open(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &Transport{Base: http.DefaultTransport}}
+	ctx := WithCassette(context.Background(), rec)
+	for _, body := range []string{"one", "two", "id-1", "id-2", "fail"} {
+		if _, _, err := do(t, client, ctx, http.MethodPost, srv.URL+"/messages?key=live-key", "", body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv.Close()
+
+	play, err := Open(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = &http.Client{Transport: &Transport{Base: offline{}}}
+	ctx = WithCassette(context.Background(), play)
+	url := srv.URL + "/messages?key=another-key" // keys are redacted, so any key matches
+	for _, tt := range []struct {
+		body, want string
+		status     int
+	}{
+		{"two", `{"echo": "two"}`, 200}, // exact bodies match out of order
+		{"one", `{"echo": "one"}`, 200},
+		{"id-9", `{"echo": "id-1"}`, 200}, // unknown bodies replay the next unused call in order
+		{"fail", `{"echo": "fail"}`, 502},
+		{"id-8", `{"echo": "id-2"}`, 200},
+	} {
+		status, body, err := do(t, client, ctx, http.MethodPost, url, "", tt.body)
+		if err != nil || body != tt.want || status != tt.status {
+			t.Errorf("replay of %q = %d %q, %v, want %d %q", tt.body, status, body, err, tt.status, tt.want)
+		}
+	}
+	// Each interaction is served once
+	if _, _, err := do(t, client, ctx, http.MethodPost, url, "", "one"); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("exhausted replay error = %v", err)
+	}
+	if _, _, err := do(t, client, ctx, http.MethodGet, url, "", ""); err == nil {
+		t.Errorf("replay matched a different method")
+	}
+	// Without a cassette requests go to Base
+	if _, _, err := do(t, client, context.Background(), http.MethodGet, url, "", ""); err == nil || !strings.Contains(err.Error(), "network access") {
+		t.Errorf("request without a cassette error = %v, want Base's", err)
+	}
+
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.json"), ModeReplay); err == nil {
+		t.Errorf("Open() of a missing cassette in replay mode succeeded")
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for name, want := range map[string]Mode{"": ModeReplay, " Record ": ModeRecord, "REPLAY": ModeReplay} {
+		if got, err := ParseMode(name); err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseMode("live"); err == nil {
+		t.Errorf("ParseMode(live) succeeded")
+	}
+}
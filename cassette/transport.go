@@ -0,0 +1,50 @@
+package cassette
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+type contextKey struct{}
+
+// WithCassette attaches c to ctx. Requests made with the returned context are recorded
+// to or replayed from c, taking precedence over the default cassette.
+func WithCassette(ctx context.Context, c *Cassette) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the cassette attached to ctx, or nil.
+func FromContext(ctx context.Context) *Cassette {
+	c, _ := ctx.Value(contextKey{}).(*Cassette)
+	return c
+}
+
+// Transport routes requests through the cassette of their context, or through Default
+// when the context has none. Without either, requests go straight to Base.
+type Transport struct {
+	Base    http.RoundTripper
+	Default *Cassette
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := FromContext(req.Context())
+	if c == nil {
+		c = t.Default
+	}
+	if c == nil {
+		return t.Base.RoundTrip(req)
+	}
+	return c.roundTrip(req, t.Base)
+}
+
+var installOnce sync.Once
+
+// Install wraps http.DefaultTransport, which every LLM, Knovvu and target client uses, so
+// cassettes attached with WithCassette take effect. def, if non-nil, applies to every
+// request without a cassette of its own. Only the first call has an effect.
+func Install(def *Cassette) {
+	installOnce.Do(func() {
+		http.DefaultTransport = &Transport{Base: http.DefaultTransport, Default: def}
+	})
+}
@@ -0,0 +1,47 @@
+-- Schema as originally created by db.InitDB. IF NOT EXISTS lets databases created
+-- before migrations were introduced adopt this migration without changes.
+CREATE TABLE IF NOT EXISTS tests (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	tenant_id TEXT NOT NULL,
+	project_id TEXT NOT NULL,
+	max_interactions INTEGER DEFAULT 10,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS scenarios (
+	id INTEGER PRIMARY KEY,
+	test_id INTEGER,
+	description TEXT NOT NULL,
+	expected_output TEXT,
+	status TEXT DEFAULT 'Not Run',
+	started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	completed_at DATETIME,
+	FOREIGN KEY (test_id) REFERENCES tests(id)
+);
+
+CREATE TABLE IF NOT EXISTS runs (
+	id INTEGER PRIMARY KEY,
+	scenario_id INTEGER,
+	status TEXT DEFAULT 'Not Run',
+	started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	completed_at DATETIME,
+	verdict TEXT,
+	verdict_reasoning TEXT,
+	prompt TEXT,
+	tester_model TEXT,
+	tested_model TEXT,
+	FOREIGN KEY (scenario_id) REFERENCES scenarios(id)
+);
+
+CREATE TABLE IF NOT EXISTS interactions (
+	id INTEGER PRIMARY KEY,
+	run_id INTEGER,
+	scenario_id INTEGER,
+	turn_number INTEGER,
+	user_message TEXT,
+	llm_response TEXT,
+	evaluation_result TEXT,
+	evaluation_reasoning TEXT,
+	FOREIGN KEY (run_id) REFERENCES runs(id)
+);
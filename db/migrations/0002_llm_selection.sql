@@ -0,0 +1,4 @@
+-- Simulator provider/model per project and the judge model of each run.
+ALTER TABLE tests ADD COLUMN llm_provider TEXT NOT NULL DEFAULT 'cohere';
+ALTER TABLE tests ADD COLUMN llm_model TEXT NOT NULL DEFAULT '';
+ALTER TABLE runs ADD COLUMN judge_model TEXT;
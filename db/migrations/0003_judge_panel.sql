@@ -0,0 +1,16 @@
+-- Judge panel agreement and the output of every judge.
+ALTER TABLE runs ADD COLUMN judge_agreement REAL;
+
+CREATE TABLE IF NOT EXISTS run_judgments (
+	id INTEGER PRIMARY KEY,
+	run_id INTEGER,
+	scenario_id INTEGER,
+	judge_model TEXT,
+	judgment TEXT,
+	confidence TEXT,
+	evidence_summary TEXT,
+	scenario_completion_score REAL,
+	conversation_quality_score REAL,
+	error TEXT,
+	FOREIGN KEY (run_id) REFERENCES runs(id)
+);
@@ -0,0 +1,12 @@
+-- Full judgment of a run and the simulator metadata of every turn.
+ALTER TABLE runs ADD COLUMN confidence TEXT;
+ALTER TABLE runs ADD COLUMN scenario_completion_score REAL;
+ALTER TABLE runs ADD COLUMN conversation_quality_score REAL;
+
+ALTER TABLE interactions ADD COLUMN fulfilled BOOLEAN DEFAULT 0;
+ALTER TABLE interactions ADD COLUMN reasoning TEXT;
+ALTER TABLE interactions ADD COLUMN strategy TEXT;
+ALTER TABLE interactions ADD COLUMN confidence TEXT;
+ALTER TABLE interactions ADD COLUMN safety_check TEXT;
+ALTER TABLE interactions ADD COLUMN error_logs TEXT;
+ALTER TABLE interactions ADD COLUMN adaptation_notes TEXT;
@@ -0,0 +1,7 @@
+-- Per-project Knovvu endpoint, channel and header settings. The tenant is tests.tenant_id.
+ALTER TABLE tests ADD COLUMN knovvu_region TEXT NOT NULL DEFAULT '';
+ALTER TABLE tests ADD COLUMN knovvu_base_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE tests ADD COLUMN knovvu_identity_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE tests ADD COLUMN knovvu_channel TEXT NOT NULL DEFAULT '';
+ALTER TABLE tests ADD COLUMN knovvu_response_type TEXT NOT NULL DEFAULT '';
+ALTER TABLE tests ADD COLUMN knovvu_headers TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Adapter used to talk to a project's bot.
+ALTER TABLE tests ADD COLUMN target_type TEXT NOT NULL DEFAULT 'knovvu';
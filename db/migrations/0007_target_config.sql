@@ -0,0 +1,2 @@
+-- Adapter-specific settings of a project's target, stored as a JSON object.
+ALTER TABLE tests ADD COLUMN target_config TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Every activity of the VA's reply to a turn (messages, cards, typing), stored as a JSON array.
+ALTER TABLE interactions ADD COLUMN va_activities TEXT;
@@ -0,0 +1,3 @@
+-- How the simulator delivered each turn: a typed message or a selected option.
+ALTER TABLE interactions ADD COLUMN action TEXT;
+ALTER TABLE interactions ADD COLUMN selected_option TEXT;
@@ -0,0 +1,2 @@
+-- Start event sent to trigger the VA greeting before the simulator speaks; empty disables it.
+ALTER TABLE tests ADD COLUMN start_event TEXT NOT NULL DEFAULT '';
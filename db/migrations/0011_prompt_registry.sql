@@ -0,0 +1,15 @@
+-- Named, versioned simulator and judge prompts, and the versions each project uses.
+CREATE TABLE IF NOT EXISTS prompts (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	version TEXT NOT NULL,
+	template TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (name, version)
+);
+
+-- Prompt references ("name@version"); empty selects the built-in prompts.
+ALTER TABLE tests ADD COLUMN simulator_prompt TEXT NOT NULL DEFAULT '';
+ALTER TABLE tests ADD COLUMN judge_prompt TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,14 @@
+-- Prompt/model experiments: each variant's runs are tagged with the experiment and variant name.
+CREATE TABLE IF NOT EXISTS experiments (
+	id INTEGER PRIMARY KEY,
+	test_id INTEGER NOT NULL,
+	name TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'pending',
+	config TEXT NOT NULL DEFAULT '{}',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	completed_at DATETIME,
+	FOREIGN KEY (test_id) REFERENCES tests(id)
+);
+
+ALTER TABLE runs ADD COLUMN experiment_id INTEGER;
+ALTER TABLE runs ADD COLUMN variant TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,24 @@
+-- Reusable simulated-user personas, the personas projects and scenarios run with, and the
+-- persona that played each run, transcript and judgment.
+CREATE TABLE IF NOT EXISTS personas (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	description TEXT NOT NULL DEFAULT '',
+	language TEXT NOT NULL DEFAULT '',
+	tone TEXT NOT NULL DEFAULT '',
+	patience TEXT NOT NULL DEFAULT '',
+	expertise TEXT NOT NULL DEFAULT '',
+	typos TEXT NOT NULL DEFAULT '',
+	verbosity TEXT NOT NULL DEFAULT '',
+	accessibility_needs TEXT NOT NULL DEFAULT '',
+	instructions TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- JSON arrays of persona names; empty runs the generic tester. Scenario lists replace the project's.
+ALTER TABLE tests ADD COLUMN personas TEXT NOT NULL DEFAULT '';
+ALTER TABLE scenarios ADD COLUMN personas TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE runs ADD COLUMN persona TEXT NOT NULL DEFAULT '';
+ALTER TABLE interactions ADD COLUMN persona TEXT NOT NULL DEFAULT '';
+ALTER TABLE run_judgments ADD COLUMN persona TEXT NOT NULL DEFAULT '';
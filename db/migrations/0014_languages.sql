@@ -0,0 +1,11 @@
+-- Conversation language (ISO 639-1 code) of projects, scenarios and runs; empty leaves it to the
+-- prompt. Scenario languages replace the persona's, which replace the project's.
+ALTER TABLE tests ADD COLUMN language TEXT NOT NULL DEFAULT '';
+ALTER TABLE scenarios ADD COLUMN language TEXT NOT NULL DEFAULT '';
+ALTER TABLE runs ADD COLUMN language TEXT NOT NULL DEFAULT '';
+
+-- VA replies detected in another language than the run's.
+ALTER TABLE runs ADD COLUMN language_mismatches INTEGER NOT NULL DEFAULT 0;
+
+-- Language detected in each VA reply; empty when undetected.
+ALTER TABLE interactions ADD COLUMN va_language TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+-- Data tables of templated scenarios: a JSON array of rows mapping variable names to values.
+-- A run expands such a scenario into one case per row.
+ALTER TABLE scenarios ADD COLUMN data_table TEXT NOT NULL DEFAULT '';
+
+-- The data row (1-based; 0 when the scenario has no data table) a run, transcript or judgment
+-- belongs to, and the values the run's scenario was rendered with, as JSON.
+ALTER TABLE runs ADD COLUMN data_row INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE runs ADD COLUMN variables TEXT NOT NULL DEFAULT '';
+ALTER TABLE interactions ADD COLUMN data_row INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE run_judgments ADD COLUMN data_row INTEGER NOT NULL DEFAULT 0;
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"database/sql"
+	"evaluator/agent"
 	repo "evaluator/repository"
 )
 
@@ -12,6 +13,12 @@ type APIEnv struct {
 	ScenarioRepo    repo.ScenarioRepo
 	TestRunRepo     repo.TestRunRepo
 	InteractionRepo repo.InteractionRepo
+	JudgmentRepo    repo.JudgmentRepo
+	PromptRepo      repo.PromptRepo
+	ExperimentRepo  repo.ExperimentRepo
+	PersonaRepo     repo.PersonaRepo
+	Runs            *agent.RunRegistry // In-flight runs that can be cancelled via the stop endpoints
+	Offline         bool               // Run every scenario against the mock target and mock LLM
 	// Add other dependencies like loggers, LLM clients if they need to be accessed by handlers
 }
 
@@ -24,5 +31,10 @@ func NewAPIEnv(dbConn *sql.DB) *APIEnv {
 		ScenarioRepo:    repo.NewScenarioRepository(dbConn),
 		TestRunRepo:     repo.NewTestRunRepository(dbConn),
 		InteractionRepo: repo.NewInteractionRepository(dbConn),
+		JudgmentRepo:    repo.NewJudgmentRepository(dbConn),
+		PromptRepo:      repo.NewPromptRepository(dbConn),
+		ExperimentRepo:  repo.NewExperimentRepository(dbConn),
+		PersonaRepo:     repo.NewPersonaRepository(dbConn),
+		Runs:            agent.NewRunRegistry(),
 	}
 }
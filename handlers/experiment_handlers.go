@@ -0,0 +1,473 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"evaluator/llm"
+	repo "evaluator/repository"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxExperimentRepeat bounds the runs per scenario and variant of one experiment.
+const maxExperimentRepeat = 20
+
+// experimentRequest is the body of POST /projects/{id}/experiments. The embedded run
+// configuration is shared by every variant; variants override prompts and models.
+type experimentRequest struct {
+	Name     string              `json:"name"`
+	Repeat   int                 `json:"repeat,omitempty"` // Runs per scenario and variant; 0 means 1
+	Variants []experimentVariant `json:"variants"`
+	runRequest
+}
+
+// experimentVariant is one arm of an experiment. Empty fields keep the project's prompts
+// and the shared run configuration's models.
+type experimentVariant struct {
+	Name            string `json:"name"`
+	SimulatorPrompt string `json:"simulator_prompt,omitempty"` // Prompt reference, e.g. "support@2"
+	JudgePrompt     string `json:"judge_prompt,omitempty"`
+	LLMProvider     string `json:"llm_provider,omitempty"`
+	LLMModel        string `json:"llm_model,omitempty"`
+	JudgeProvider   string `json:"judge_provider,omitempty"`
+	JudgeModel      string `json:"judge_model,omitempty"`
+}
+
+// experimentConfig is what an experiment stores in experiments.config: the resolved
+// identity of every variant, so the comparison shows exactly what was compared.
+type experimentConfig struct {
+	Repeat   int             `json:"repeat"`
+	Variants []variantRecord `json:"variants"`
+}
+
+type variantRecord struct {
+	Name            string `json:"name"`
+	TesterModel     string `json:"tester_model"`
+	JudgeModel      string `json:"judge_model"`
+	SimulatorPrompt string `json:"simulator_prompt"`
+	JudgePrompt     string `json:"judge_prompt"`
+}
+
+// experimentArm is a resolved variant, ready to run.
+type experimentArm struct {
+	Name    string
+	Config  runRequest
+	Models  runModels
+	Prompts runPrompts
+}
+
+// record returns the variant's identity as stored in the experiment config.
+func (a experimentArm) record() variantRecord {
+	return variantRecord{
+		Name:            a.Name,
+		TesterModel:     llm.ModelID(a.Models.TesterProvider, a.Models.TesterModel),
+		JudgeModel:      a.Models.judgeName(),
+		SimulatorPrompt: a.Prompts.Simulator.Ref(),
+		JudgePrompt:     a.Prompts.Judge.Ref(),
+	}
+}
+
+// resolveExperiment validates the request and resolves the models and prompts of every variant.
+func (env *APIEnv) resolveExperiment(proj *repo.Test, req *experimentRequest) ([]experimentArm, error) {
+	if len(req.Variants) < 2 {
+		return nil, fmt.Errorf("an experiment needs at least two variants")
+	}
+	if req.Repeat == 0 {
+		req.Repeat = 1
+	}
+	if req.Repeat < 0 || req.Repeat > maxExperimentRepeat {
+		return nil, fmt.Errorf("repeat must be between 1 and %d", maxExperimentRepeat)
+	}
+
+	arms := make([]experimentArm, 0, len(req.Variants))
+	seen := make(map[string]bool)
+	for i, v := range req.Variants {
+		name := strings.TrimSpace(v.Name)
+		if name == "" {
+			name = fmt.Sprintf("variant-%d", i+1)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate variant name %q", name)
+		}
+		seen[name] = true
+
+		cfg := req.runRequest
+		if v.LLMProvider != "" {
+			cfg.LLMProvider, cfg.LLMModel = v.LLMProvider, ""
+		}
+		if v.LLMModel != "" {
+			cfg.LLMModel = v.LLMModel
+		}
+		if v.JudgeProvider != "" {
+			cfg.JudgeProvider, cfg.JudgeModel = v.JudgeProvider, ""
+		}
+		if v.JudgeModel != "" {
+			cfg.JudgeModel = v.JudgeModel
+		}
+		models, err := cfg.resolveModels(proj)
+		if err != nil {
+			return nil, fmt.Errorf("variant %s: %w", name, err)
+		}
+		if env.Offline {
+			models = models.offline()
+		}
+
+		selection := *proj
+		if v.SimulatorPrompt != "" {
+			selection.SimulatorPrompt = v.SimulatorPrompt
+		}
+		if v.JudgePrompt != "" {
+			selection.JudgePrompt = v.JudgePrompt
+		}
+		prompts, err := env.resolvePrompts(&selection)
+		if err != nil {
+			return nil, fmt.Errorf("variant %s: %w", name, err)
+		}
+		arms = append(arms, experimentArm{Name: name, Config: cfg, Models: models, Prompts: prompts})
+	}
+	return arms, nil
+}
+
+// handleExperiments handles /projects/{id}/experiments: POST starts an experiment, GET lists
+// the project's experiments, and GET /projects/{id}/experiments/{experimentID} compares its variants.
+func (env *APIEnv) handleExperiments(w http.ResponseWriter, r *http.Request, projectID int, parts []string) {
+	if len(parts) > 2 && parts[2] != "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed for experiment, expected GET", http.StatusMethodNotAllowed)
+			return
+		}
+		experimentID, err := strconv.Atoi(parts[2])
+		if err != nil {
+			http.Error(w, "Invalid experiment ID format", http.StatusBadRequest)
+			return
+		}
+		env.handleGetExperiment(w, projectID, experimentID)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		experiments, err := env.ExperimentRepo.ListByTest(projectID)
+		if err != nil {
+			log.Printf("[EXPERIMENT][ERROR] Failed to list experiments for project_id=%d: %v", projectID, err)
+			http.Error(w, "Failed to retrieve experiments", http.StatusInternalServerError)
+			return
+		}
+		if experiments == nil {
+			experiments = []repo.Experiment{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(experiments)
+	case http.MethodPost:
+		env.handleStartExperiment(w, r, projectID)
+	default:
+		http.Error(w, "Method not allowed for experiments, expected GET or POST", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleStartExperiment runs every scenario of the project once per variant and repetition.
+// Each scenario run is stored like a single scenario run, tagged with the experiment and variant,
+// and scenarios are expanded into one run per data table row and persona.
+func (env *APIEnv) handleStartExperiment(w http.ResponseWriter, r *http.Request, projectID int) {
+	var req experimentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		log.Printf("[EXPERIMENT][ERROR] Invalid experiment configuration for project_id=%d: %v", projectID, err)
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	proj, err := env.TestRepo.GetTestByID(projectID)
+	if err != nil || proj == nil {
+		log.Printf("[EXPERIMENT][ERROR] Failed to fetch project_id=%d: %v", projectID, err)
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	}
+	arms, err := env.resolveExperiment(proj, &req)
+	if err != nil {
+		log.Printf("[EXPERIMENT][ERROR] Invalid experiment for project_id=%d: %v", projectID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	scenarios, err := env.ScenarioRepo.GetScenariosByTestID(projectID)
+	if err != nil {
+		log.Printf("[EXPERIMENT][ERROR] Failed to fetch scenarios for project_id=%d: %v", projectID, err)
+		http.Error(w, "Failed to retrieve scenarios", http.StatusInternalServerError)
+		return
+	}
+	if len(scenarios) == 0 {
+		http.Error(w, "Project has no scenarios", http.StatusBadRequest)
+		return
+	}
+	executions := make([][]scenarioRun, len(scenarios))
+	totalRuns := 0
+	for i := range scenarios {
+		if executions[i], err = env.scenarioRuns(proj, &scenarios[i]); err != nil {
+			log.Printf("[EXPERIMENT][ERROR] Invalid persona selection or data table for scenario_id=%s: %v", scenarios[i].ID, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		totalRuns += len(executions[i]) * len(arms) * req.Repeat
+	}
+	tape, err := req.openCassette()
+	if err != nil {
+		log.Printf("[EXPERIMENT][ERROR] Failed to open cassette for project_id=%d: %v", projectID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cfg := experimentConfig{Repeat: req.Repeat}
+	for _, arm := range arms {
+		cfg.Variants = append(cfg.Variants, arm.record())
+	}
+	cfgJSON, _ := json.Marshal(cfg)
+	experimentID, err := env.ExperimentRepo.Create(projectID, strings.TrimSpace(req.Name), string(cfgJSON))
+	if err != nil {
+		log.Printf("[EXPERIMENT][ERROR] Failed to create experiment for project_id=%d: %v", projectID, err)
+		http.Error(w, "Failed to create experiment", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[EXPERIMENT] Experiment %d created for project_id=%d: %d variants, %d scenarios, repeat %d", experimentID, projectID, len(arms), len(scenarios), req.Repeat)
+
+	// The timeout of the shared run configuration applies to the whole experiment.
+	ctx, cancel := req.runContext(tape)
+	handle := env.Runs.Register(projectID, 0, cancel)
+	go func() {
+		defer env.Runs.Unregister(handle)
+		defer cancel()
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("[EXPERIMENT][GOROUTINE][PANIC] Recovered from panic in experiment %d: %v", experimentID, r)
+				env.ExperimentRepo.UpdateStatus(experimentID, "failed")
+			}
+		}()
+
+		bot, err := env.runTarget(proj)
+		if err != nil {
+			log.Printf("[EXPERIMENT][GOROUTINE][ERROR] Failed to create target for experiment %d: %v", experimentID, err)
+			env.ExperimentRepo.UpdateStatus(experimentID, "failed")
+			return
+		}
+		setups := make([]scenarioSetup, len(arms))
+		for i, arm := range arms {
+			clients, err := arm.Models.clients(arm.Config)
+			if err != nil {
+				log.Printf("[EXPERIMENT][GOROUTINE][ERROR] Failed to create LLM clients for variant %s of experiment %d: %v", arm.Name, experimentID, err)
+				env.ExperimentRepo.UpdateStatus(experimentID, "failed")
+				return
+			}
+			setups[i] = scenarioSetup{Project: proj, Clients: clients, Prompts: arm.Prompts, Target: bot, JudgeName: arm.Models.judgeName()}
+		}
+
+		// Variants take turns on each scenario, so changes of the VA during the experiment
+		// affect all of them alike.
+		status := "completed"
+		stopped := make(map[int]bool) // Scenarios stopped on their own are skipped from then on
+	experiment:
+		for rep := 1; rep <= req.Repeat; rep++ {
+			for i := range scenarios {
+				sc := &scenarios[i]
+				sID, err := strconv.Atoi(sc.ID)
+				if err != nil || stopped[sID] {
+					continue
+				}
+				scenarioCtx, cancelScenario := context.WithCancel(ctx)
+				env.Runs.StartScenario(handle, sID, cancelScenario)
+			scenario:
+				for _, execution := range executions[i] {
+					for j, arm := range arms {
+						if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+							status = "timed_out"
+							cancelScenario()
+							break experiment
+						} else if ctx.Err() != nil {
+							status = "cancelled"
+							cancelScenario()
+							break experiment
+						} else if scenarioCtx.Err() != nil {
+							break scenario
+						}
+						metadata := arm.Models.runMetadata()
+						metadata["prompt"] = arm.Prompts.record()
+						metadata["experiment_id"] = experimentID
+						metadata["variant"] = arm.Name
+						execution.tag(proj, metadata)
+						runID, err := env.TestRunRepo.CreateTestRun(sID, metadata)
+						if err != nil {
+							log.Printf("[EXPERIMENT][GOROUTINE][ERROR] Failed to create run for scenario_id=%d, variant %s: %v", sID, arm.Name, err)
+							continue
+						}
+						env.TestRunRepo.UpdateTestRunStatus(runID, "running", nil, nil)
+						env.Runs.AddRun(handle, sID, runID)
+						setup := setups[j]
+						setup.Persona, setup.DataRow = execution.Persona, execution.Case.Row
+						outcome := env.executeScenarioRun(scenarioCtx, runID, sID, execution.Case.Scenario, setup)
+						log.Printf("[EXPERIMENT][GOROUTINE] Experiment %d, repetition %d, scenario_id=%d, data_row %d, persona %q, variant %s: %s in %d turns (run_id=%d)",
+							experimentID, rep, sID, execution.Case.Row, personaName(execution.Persona), arm.Name, outcome.Status, outcome.Turns, runID)
+					}
+				}
+				if scenarioCtx.Err() != nil && ctx.Err() == nil {
+					log.Printf("[EXPERIMENT][GOROUTINE] Scenario_id=%d stopped in experiment %d; skipping its remaining runs", sID, experimentID)
+					stopped[sID] = true
+				}
+				env.Runs.EndScenario(handle)
+				cancelScenario()
+			}
+		}
+		if err := env.ExperimentRepo.UpdateStatus(experimentID, status); err != nil {
+			log.Printf("[EXPERIMENT][GOROUTINE][ERROR] Failed to update status of experiment %d: %v", experimentID, err)
+		}
+		log.Printf("[EXPERIMENT][GOROUTINE] Experiment %d finished: %s", experimentID, status)
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"experiment_id": experimentID,
+		"status":        "started",
+		"runs":          totalRuns,
+	})
+}
+
+// variantStats aggregates the finished runs of one variant. Cancelled and unfinished runs
+// are not counted; averages of scores only include runs a judge scored.
+type variantStats struct {
+	Runs               int     `json:"runs"`
+	Passed             int     `json:"passed"`
+	PassRate           float64 `json:"pass_rate"`
+	AvgCompletionScore float64 `json:"avg_completion_score"`
+	AvgQualityScore    float64 `json:"avg_quality_score"`
+	AvgTurns           float64 `json:"avg_turns"`
+
+	judged          int
+	completionTotal float64
+	qualityTotal    float64
+	turnsTotal      int
+}
+
+func (s *variantStats) add(run repo.ExperimentRun) {
+	s.Runs++
+	if strings.EqualFold(run.Verdict, llm.JudgementPass) {
+		s.Passed++
+	}
+	if run.Judged {
+		s.judged++
+		s.completionTotal += run.ScenarioCompletionScore
+		s.qualityTotal += run.ConversationQualityScore
+	}
+	s.turnsTotal += run.Turns
+	s.PassRate = float64(s.Passed) / float64(s.Runs)
+	s.AvgTurns = float64(s.turnsTotal) / float64(s.Runs)
+	if s.judged > 0 {
+		s.AvgCompletionScore = s.completionTotal / float64(s.judged)
+		s.AvgQualityScore = s.qualityTotal / float64(s.judged)
+	}
+}
+
+// scenarioComparison puts the variants' results for one scenario side by side.
+type scenarioComparison struct {
+	ScenarioID  int                      `json:"scenario_id"`
+	Description string                   `json:"description"`
+	Variants    map[string]*variantStats `json:"variants"`
+}
+
+// experimentComparison is the response of GET /projects/{id}/experiments/{experimentID}.
+type experimentComparison struct {
+	repo.Experiment
+	Repeat    int                      `json:"repeat"`
+	Variants  []variantRecord          `json:"variants"`
+	Scenarios []scenarioComparison     `json:"scenarios"`
+	Overall   map[string]*variantStats `json:"overall"`
+	Pending   int                      `json:"pending"` // Runs still in progress
+}
+
+// compareExperiment aggregates the experiment's runs per scenario and variant, in the
+// project's scenario order.
+func compareExperiment(cfg experimentConfig, scenarios []repo.Scenario, runs []repo.ExperimentRun) ([]scenarioComparison, map[string]*variantStats, int) {
+	newStats := func() map[string]*variantStats {
+		m := make(map[string]*variantStats, len(cfg.Variants))
+		for _, v := range cfg.Variants {
+			m[v.Name] = &variantStats{}
+		}
+		return m
+	}
+
+	var rows []scenarioComparison
+	index := make(map[int]int)
+	for _, sc := range scenarios {
+		id, err := strconv.Atoi(sc.ID)
+		if err != nil {
+			continue
+		}
+		index[id] = len(rows)
+		rows = append(rows, scenarioComparison{ScenarioID: id, Description: sc.Description, Variants: newStats()})
+	}
+
+	overall := newStats()
+	pending := 0
+	for _, run := range runs {
+		// Timed-out runs are finished, failed runs; cancelled ones are left out
+		switch run.Status {
+		case "completed", "failed", "timed_out":
+		case "cancelled":
+			continue
+		default:
+			pending++
+			continue
+		}
+		i, ok := index[run.ScenarioID]
+		if !ok { // Scenario deleted since the experiment ran
+			i = len(rows)
+			index[run.ScenarioID] = i
+			rows = append(rows, scenarioComparison{ScenarioID: run.ScenarioID, Variants: newStats()})
+		}
+		if rows[i].Variants[run.Variant] == nil {
+			rows[i].Variants[run.Variant] = &variantStats{}
+		}
+		if overall[run.Variant] == nil {
+			overall[run.Variant] = &variantStats{}
+		}
+		rows[i].Variants[run.Variant].add(run)
+		overall[run.Variant].add(run)
+	}
+	return rows, overall, pending
+}
+
+func (env *APIEnv) handleGetExperiment(w http.ResponseWriter, projectID, experimentID int) {
+	experiment, err := env.ExperimentRepo.GetByID(experimentID)
+	if err != nil {
+		log.Printf("[EXPERIMENT][ERROR] Failed to fetch experiment %d: %v", experimentID, err)
+		http.Error(w, "Failed to retrieve experiment", http.StatusInternalServerError)
+		return
+	}
+	if experiment == nil || experiment.TestID != projectID {
+		http.Error(w, "Experiment not found", http.StatusNotFound)
+		return
+	}
+	var cfg experimentConfig
+	if err := json.Unmarshal([]byte(experiment.Config), &cfg); err != nil {
+		log.Printf("[EXPERIMENT][ERROR] Invalid config of experiment %d: %v", experimentID, err)
+	}
+	scenarios, err := env.ScenarioRepo.GetScenariosByTestID(projectID)
+	if err != nil {
+		log.Printf("[EXPERIMENT][ERROR] Failed to fetch scenarios for project_id=%d: %v", projectID, err)
+		http.Error(w, "Failed to retrieve scenarios", http.StatusInternalServerError)
+		return
+	}
+	runs, err := env.ExperimentRepo.GetRuns(experimentID)
+	if err != nil {
+		log.Printf("[EXPERIMENT][ERROR] Failed to fetch runs of experiment %d: %v", experimentID, err)
+		http.Error(w, "Failed to retrieve experiment runs", http.StatusInternalServerError)
+		return
+	}
+
+	experiment.Config = "" // Returned decoded as repeat and variants
+	result := experimentComparison{Experiment: *experiment, Repeat: cfg.Repeat, Variants: cfg.Variants}
+	result.Scenarios, result.Overall, result.Pending = compareExperiment(cfg, scenarios, runs)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
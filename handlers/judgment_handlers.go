@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	repo "evaluator/repository"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ListJudgmentsByTestRunHandler handles GET /api/judgments/{testRunID}
+// It returns the verdict of every judge for each scenario of a run.
+func (env *APIEnv) ListJudgmentsByTestRunHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Path expected: /api/judgments/{testRunID}
+	testRunIDStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/judgments/"), "/")
+	if testRunIDStr == "" {
+		log.Printf("[GET-JUDGMENTS] Missing testRunID in path: %s", r.URL.Path)
+		http.Error(w, "Missing testRunID in path", http.StatusBadRequest)
+		return
+	}
+	testRunID, err := strconv.Atoi(testRunIDStr)
+	if err != nil {
+		log.Printf("[GET-JUDGMENTS] Invalid testRunID in path '%s': %v", testRunIDStr, err)
+		http.Error(w, "Invalid testRunID format", http.StatusBadRequest)
+		return
+	}
+
+	judgments, err := env.JudgmentRepo.GetByTestRunID(testRunID)
+	if err != nil {
+		log.Printf("[GET-JUDGMENTS] Error fetching judgments for testRunID=%d: %v", testRunID, err)
+		http.Error(w, "Failed to retrieve judgments: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if judgments == nil {
+		judgments = []repo.Judgment{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(judgments)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"evaluator/llm"
+	repo "evaluator/repository"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ScenarioLanguageHandler handles PUT /scenarios/{id}/language with {"language": "tr"}.
+// An empty language makes the scenario use its persona's or project's language.
+func (env *APIEnv) ScenarioLanguageHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Methods", "PUT, OPTIONS")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != "PUT" {
+		http.Error(w, "Method Not Allowed, expected PUT", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/scenarios/"), "/")
+	scenarioID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid scenario ID format", http.StatusBadRequest)
+		return
+	}
+	var body struct {
+		Language string `json:"language"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	language, err := llm.NormalizeLanguage(body.Language)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if scenario, err := env.ScenarioRepo.GetScenarioByID(scenarioID); err != nil || scenario == nil {
+		http.Error(w, "Scenario not found", http.StatusNotFound)
+		return
+	}
+	scenario, err := env.ScenarioRepo.UpdateScenario(scenarioID, map[string]interface{}{"language": language})
+	if err != nil {
+		log.Printf("[LANGUAGE][ERROR] Failed to set language of scenario_id=%d: %v", scenarioID, err)
+		http.Error(w, "Failed to update scenario", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"id": scenario.ID, "language": language})
+}
+
+// runLanguage returns the language a scenario is run in: the scenario's, else the persona's,
+// else the project's. Empty leaves the language to the prompt.
+func runLanguage(proj *repo.Test, sc *repo.Scenario, persona *repo.Persona) string {
+	if sc.Language != "" {
+		return sc.Language
+	}
+	if persona != nil && persona.Language != "" {
+		return persona.Language
+	}
+	return proj.Language
+}
+
+// languageMismatches counts the VA replies detected in another language than the run's.
+// Replies whose language could not be detected are not counted.
+func languageMismatches(language string, history []llm.HistoryItem) int {
+	if language == "" {
+		return 0
+	}
+	mismatches := 0
+	for _, h := range history {
+		if h.VALanguage != "" && h.VALanguage != language {
+			mismatches++
+		}
+	}
+	return mismatches
+}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"evaluator/llm"
+	repo "evaluator/repository"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PersonasHandler handles /api/personas: GET lists the persona library, POST creates a persona.
+func (env *APIEnv) PersonasHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+
+	switch r.Method {
+	case "OPTIONS":
+		w.WriteHeader(http.StatusOK)
+	case "GET":
+		personas, err := env.PersonaRepo.List()
+		if err != nil {
+			log.Printf("[PERSONAS][ERROR] Failed to list personas: %v", err)
+			http.Error(w, "Failed to retrieve personas: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if personas == nil {
+			personas = []repo.Persona{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(personas)
+	case "POST":
+		var p repo.Persona
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			log.Printf("[PERSONAS][ERROR] Failed to decode persona: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		p.Name = strings.TrimSpace(p.Name)
+		if err := validatePersona(&p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := env.PersonaRepo.Create(&p); err != nil {
+			log.Printf("[PERSONAS][ERROR] Failed to create persona %s: %v", p.Name, err)
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("[PERSONAS] Created persona %s", p.Name)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(p)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// PersonaHandler handles /api/personas/{id}: GET returns a persona, PUT replaces its traits
+// and DELETE removes it. Names are fixed because projects and scenarios refer to them.
+func (env *APIEnv) PersonaHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, OPTIONS")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/personas/"), "/")
+	personaID, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[PERSONAS][ERROR] Invalid persona ID in path '%s': %v", idStr, err)
+		http.Error(w, "Invalid persona ID format", http.StatusBadRequest)
+		return
+	}
+	p, err := env.PersonaRepo.GetByID(personaID)
+	if err != nil {
+		log.Printf("[PERSONAS][ERROR] Failed to fetch persona id=%d: %v", personaID, err)
+		http.Error(w, "Failed to retrieve persona", http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.Error(w, "Persona not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(p)
+	case "PUT":
+		var update repo.Persona
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if name := strings.TrimSpace(update.Name); name != "" && name != p.Name {
+			http.Error(w, "Persona names can't change; create a new persona instead", http.StatusConflict)
+			return
+		}
+		update.ID, update.Name, update.CreatedAt = p.ID, p.Name, p.CreatedAt
+		if err := validatePersona(&update); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := env.PersonaRepo.Update(&update); err != nil {
+			log.Printf("[PERSONAS][ERROR] Failed to update persona id=%d: %v", personaID, err)
+			http.Error(w, "Failed to update persona", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(update)
+	case "DELETE":
+		if err := env.PersonaRepo.Delete(personaID); err != nil {
+			if errors.Is(err, repo.ErrPersonaInUse) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			log.Printf("[PERSONAS][ERROR] Failed to delete persona id=%d: %v", personaID, err)
+			http.Error(w, "Failed to delete persona", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("[PERSONAS] Deleted persona %s", p.Name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func validatePersona(p *repo.Persona) error {
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	language, err := llm.NormalizeLanguage(p.Language)
+	if err != nil {
+		return err
+	}
+	p.Language = language
+	if err := llm.ValidatePersonaTrait("patience", p.Patience, llm.PersonaPatience); err != nil {
+		return err
+	}
+	if err := llm.ValidatePersonaTrait("expertise", p.Expertise, llm.PersonaExpertise); err != nil {
+		return err
+	}
+	if err := llm.ValidatePersonaTrait("typos", p.Typos, llm.PersonaTypos); err != nil {
+		return err
+	}
+	return llm.ValidatePersonaTrait("verbosity", p.Verbosity, llm.PersonaVerbosity)
+}
+
+// ScenarioPersonasHandler handles PUT /scenarios/{id}/personas with {"personas": ["name", ...]}.
+// An empty list makes the scenario use the project's personas.
+func (env *APIEnv) ScenarioPersonasHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Methods", "PUT, OPTIONS")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != "PUT" {
+		http.Error(w, "Method Not Allowed, expected PUT", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/scenarios/"), "/")
+	scenarioID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid scenario ID format", http.StatusBadRequest)
+		return
+	}
+	var body struct {
+		Personas []string `json:"personas"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	names, err := env.validatePersonaNames(body.Personas)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if scenario, err := env.ScenarioRepo.GetScenarioByID(scenarioID); err != nil || scenario == nil {
+		http.Error(w, "Scenario not found", http.StatusNotFound)
+		return
+	}
+	scenario, err := env.ScenarioRepo.UpdateScenario(scenarioID, map[string]interface{}{"personas": names})
+	if err != nil {
+		log.Printf("[PERSONAS][ERROR] Failed to set personas of scenario_id=%d: %v", scenarioID, err)
+		http.Error(w, "Failed to update scenario", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"id": scenario.ID, "personas": names})
+}
+
+// validatePersonaNames trims a persona selection and checks that every persona exists.
+func (env *APIEnv) validatePersonaNames(names []string) ([]string, error) {
+	seen := make(map[string]bool)
+	cleaned := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		cleaned = append(cleaned, name)
+	}
+	if _, err := env.loadPersonas(cleaned); err != nil {
+		return nil, err
+	}
+	return cleaned, nil
+}
+
+func (env *APIEnv) loadPersonas(names []string) ([]*repo.Persona, error) {
+	personas := make([]*repo.Persona, 0, len(names))
+	for _, name := range names {
+		p, err := env.PersonaRepo.GetByName(name)
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			return nil, fmt.Errorf("persona %q not found", name)
+		}
+		personas = append(personas, p)
+	}
+	return personas, nil
+}
+
+// runPersonas returns the personas a scenario is run with: its own selection, else the
+// project's. A single nil entry stands for the generic tester.
+func (env *APIEnv) runPersonas(proj *repo.Test, sc *repo.Scenario) ([]*repo.Persona, error) {
+	names := sc.Personas
+	if len(names) == 0 {
+		names = proj.Personas
+	}
+	if len(names) == 0 {
+		return []*repo.Persona{nil}, nil
+	}
+	return env.loadPersonas(names)
+}
+
+// personaName is the name recorded for a run's persona; empty for the generic tester.
+func personaName(p *repo.Persona) string {
+	if p == nil {
+		return ""
+	}
+	return p.Name
+}
+
+// personaInput converts a stored persona into the simulator's input. Its language is sent
+// as the run's language instead; see runLanguage.
+func personaInput(p *repo.Persona) *llm.Persona {
+	if p == nil {
+		return nil
+	}
+	return &llm.Persona{
+		Name:               p.Name,
+		Description:        p.Description,
+		Tone:               p.Tone,
+		Patience:           p.Patience,
+		Expertise:          p.Expertise,
+		Typos:              p.Typos,
+		Verbosity:          p.Verbosity,
+		AccessibilityNeeds: p.AccessibilityNeeds,
+		Instructions:       p.Instructions,
+	}
+}
+
+// combineStatus folds the status of one persona's run into a scenario's status: the
+// scenario passes only if every persona passed, and otherwise keeps the first other status.
+func combineStatus(current, next string) string {
+	if current == "" || current == llm.JudgementPass {
+		return next
+	}
+	return current
+}
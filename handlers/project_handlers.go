@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"evaluator/llm"
 	repo "evaluator/repository"
+	"evaluator/target"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -42,6 +45,7 @@ func (env *APIEnv) ListCreateProjectsHandler(w http.ResponseWriter, r *http.Requ
 // - /projects/{id} (for PUT, DELETE) -> delegates to ProjectItemActionHandler logic
 // - /projects/{id}/run-test -> delegates to ProjectTestRunHandler logic for run-test
 // - /projects/{id}/test-status -> delegates to ProjectTestRunHandler logic for test-status
+// - /projects/{id}/stop-test -> cancels the project's in-flight runs
 func (env *APIEnv) ProjectDispatchHandler(w http.ResponseWriter, r *http.Request) {
 	// CORS headers are set by the specific sub-handlers if needed, or can be set here once.
 	// For simplicity, let sub-handlers manage their specific CORS needs if they differ.
@@ -99,6 +103,16 @@ func (env *APIEnv) ProjectDispatchHandler(w http.ResponseWriter, r *http.Request
 			}
 			env.handleGetProjectTestStatus(w, r, projectID) // from test_run_handlers.go
 			return
+		case "stop-test":
+			if r.Method != http.MethodPost {
+				http.Error(w, "Method not allowed for stop-test, expected POST", http.StatusMethodNotAllowed)
+				return
+			}
+			env.handleStopProjectTest(w, r, projectID) // from test_run_handlers.go
+			return
+		case "experiments":
+			env.handleExperiments(w, r, projectID, parts) // from experiment_handlers.go
+			return
 		default:
 			log.Printf("[PROJECTS][DISPATCH][WARN] Unknown action '%s' for project %d", action, projectID)
 			http.NotFound(w, r)
@@ -134,7 +148,51 @@ func (env *APIEnv) handleCreateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := env.TestRepo.CreateTest(newTest.Name, newTest.TenantID, newTest.ProjectID, newTest.MaxInteractions)
+	if newTest.LLMProvider != "" {
+		provider, err := llm.ParseProvider(newTest.LLMProvider)
+		if err != nil {
+			log.Printf("[PROJECTS][HELPER][ERROR] Invalid LLM provider for new project: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		newTest.LLMProvider = string(provider)
+	}
+
+	targetType, err := target.ParseTargetType(newTest.TargetType)
+	if err != nil {
+		log.Printf("[PROJECTS][HELPER][ERROR] Invalid target type for new project: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	newTest.TargetType = string(targetType)
+	if _, err := newTarget(&newTest); err != nil {
+		log.Printf("[PROJECTS][HELPER][ERROR] Invalid target configuration for new project: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	newTest.SimulatorPrompt, newTest.JudgePrompt = strings.TrimSpace(newTest.SimulatorPrompt), strings.TrimSpace(newTest.JudgePrompt)
+	if _, err := env.resolvePrompts(&newTest); err != nil {
+		log.Printf("[PROJECTS][HELPER][ERROR] Invalid prompt selection for new project: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	personas, err := env.validatePersonaNames(newTest.Personas)
+	if err != nil {
+		log.Printf("[PROJECTS][HELPER][ERROR] Invalid personas for new project: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	language, err := llm.NormalizeLanguage(newTest.Language)
+	if err != nil {
+		log.Printf("[PROJECTS][HELPER][ERROR] Invalid language for new project: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := env.TestRepo.CreateTest(newTest.Name, newTest.TenantID, newTest.ProjectID, newTest.MaxInteractions, newTest.LLMProvider, newTest.LLMModel, newTest.TargetType, newTest.TargetConfig, strings.TrimSpace(newTest.StartEvent), newTest.PromptSelection, personas, language, newTest.KnovvuSettings)
 	if err != nil {
 		log.Printf("[PROJECTS][HELPER][ERROR] Failed to create project: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -149,26 +207,46 @@ func (env *APIEnv) handleCreateProject(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("[PROJECTS][HELPER][INFO] Project created: id=%d, name=%s", createdTest.ID, createdTest.Name)
-	projectResponse := map[string]any{
-		"id":               createdTest.ID,
-		"title":            createdTest.Name,
-		"tenant_id":        createdTest.TenantID,
-		"project_id":       createdTest.ProjectID,
-		"max_interactions": createdTest.MaxInteractions,
-		"created_at":       createdTest.CreatedAt,
-		"scenarios":        []repo.Scenario{},
-	}
+	projectResponse := projectItem(createdTest, []repo.Scenario{})
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(projectResponse)
 }
 
+// projectItem is the JSON representation of a project returned by the project endpoints.
+func projectItem(t *repo.Test, scenarios []repo.Scenario) map[string]any {
+	return map[string]any{
+		"id":                   t.ID,
+		"title":                t.Name,
+		"tenant_id":            t.TenantID,
+		"project_id":           t.ProjectID,
+		"max_interactions":     t.MaxInteractions,
+		"llm_provider":         t.LLMProvider,
+		"llm_model":            t.LLMModel,
+		"target_type":          t.TargetType,
+		"target_config":        t.TargetConfig,
+		"start_event":          t.StartEvent,
+		"simulator_prompt":     t.SimulatorPrompt,
+		"judge_prompt":         t.JudgePrompt,
+		"personas":             t.Personas,
+		"language":             t.Language,
+		"knovvu_region":        t.KnovvuRegion,
+		"knovvu_base_url":      t.KnovvuBaseURL,
+		"knovvu_identity_url":  t.KnovvuIdentityURL,
+		"knovvu_channel":       t.KnovvuChannel,
+		"knovvu_response_type": t.KnovvuResponseType,
+		"knovvu_headers":       t.KnovvuHeaders,
+		"created_at":           t.CreatedAt,
+		"scenarios":            scenarios,
+	}
+}
+
 func (env *APIEnv) handleListProjects(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[PROJECTS][HELPER][INFO] Listing all projects (GET /projects) from %s", r.RemoteAddr)
 	w.Header().Set("Content-Type", "application/json")
 
-	rows, err := env.DB.Query("SELECT id, name, tenant_id, project_id, max_interactions, created_at FROM tests")
+	rows, err := env.DB.Query("SELECT " + repo.TestColumns + " FROM tests")
 	if err != nil {
 		log.Printf("[PROJECTS][HELPER][ERROR] Failed to query projects: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -178,8 +256,8 @@ func (env *APIEnv) handleListProjects(w http.ResponseWriter, r *http.Request) {
 
 	var projectsResponse []map[string]any
 	for rows.Next() {
-		var t repo.Test
-		if err := rows.Scan(&t.ID, &t.Name, &t.TenantID, &t.ProjectID, &t.MaxInteractions, &t.CreatedAt); err != nil {
+		t, err := repo.ScanTest(rows)
+		if err != nil {
 			log.Printf("[PROJECTS][HELPER][ERROR] Failed to scan project row: %v", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -189,16 +267,7 @@ func (env *APIEnv) handleListProjects(w http.ResponseWriter, r *http.Request) {
 			log.Printf("[PROJECTS][HELPER][WARN] Failed to fetch scenarios for project id=%d: %v", t.ID, errScn)
 		}
 
-		projectItem := map[string]any{
-			"id":               t.ID,
-			"title":            t.Name,
-			"tenant_id":        t.TenantID,
-			"project_id":       t.ProjectID,
-			"max_interactions": t.MaxInteractions,
-			"created_at":       t.CreatedAt,
-			"scenarios":        scenarios,
-		}
-		projectsResponse = append(projectsResponse, projectItem)
+		projectsResponse = append(projectsResponse, projectItem(t, scenarios))
 	}
 	if rows.Err() != nil {
 		log.Printf("[PROJECTS][HELPER][ERROR] Error iterating project rows: %v", rows.Err())
@@ -219,6 +288,118 @@ func (env *APIEnv) handleUpdateProject(w http.ResponseWriter, r *http.Request, p
 		return
 	}
 
+	if p, ok := updates["llm_provider"]; ok {
+		name, _ := p.(string)
+		provider, err := llm.ParseProvider(name)
+		if err != nil {
+			log.Printf("[PROJECTS][HELPER][ERROR] Invalid LLM provider for project id=%d: %v", projectID, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		updates["llm_provider"] = string(provider)
+	}
+
+	if t, ok := updates["target_type"]; ok {
+		name, _ := t.(string)
+		targetType, err := target.ParseTargetType(name)
+		if err != nil {
+			log.Printf("[PROJECTS][HELPER][ERROR] Invalid target type for project id=%d: %v", projectID, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		updates["target_type"] = string(targetType)
+	}
+
+	if e, ok := updates["start_event"]; ok {
+		event, _ := e.(string)
+		updates["start_event"] = strings.TrimSpace(event)
+	}
+
+	for column, kind := range map[string]string{"simulator_prompt": llm.PromptKindSimulator, "judge_prompt": llm.PromptKindJudge} {
+		v, ok := updates[column]
+		if !ok {
+			continue
+		}
+		ref, _ := v.(string)
+		ref = strings.TrimSpace(ref)
+		if _, err := env.resolvePrompt(ref, kind); err != nil {
+			log.Printf("[PROJECTS][HELPER][ERROR] Invalid %s for project id=%d: %v", column, projectID, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		updates[column] = ref
+	}
+
+	if p, ok := updates["personas"]; ok {
+		var names []string
+		if list, ok := p.([]interface{}); ok {
+			for _, v := range list {
+				name, _ := v.(string)
+				names = append(names, name)
+			}
+		} else if p != nil {
+			http.Error(w, "personas must be a list of persona names", http.StatusBadRequest)
+			return
+		}
+		names, err := env.validatePersonaNames(names)
+		if err != nil {
+			log.Printf("[PROJECTS][HELPER][ERROR] Invalid personas for project id=%d: %v", projectID, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		encoded, err := repo.EncodePersonaNames(names)
+		if err != nil {
+			http.Error(w, "Invalid personas", http.StatusBadRequest)
+			return
+		}
+		updates["personas"] = encoded
+	}
+
+	if l, ok := updates["language"]; ok {
+		value, _ := l.(string)
+		language, err := llm.NormalizeLanguage(value)
+		if err != nil {
+			log.Printf("[PROJECTS][HELPER][ERROR] Invalid language for project id=%d: %v", projectID, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		updates["language"] = language
+	}
+
+	if c, ok := updates["target_config"]; ok {
+		encoded := ""
+		if c != nil {
+			b, err := json.Marshal(c)
+			if err != nil {
+				log.Printf("[PROJECTS][HELPER][ERROR] Failed to encode target_config for project id=%d: %v", projectID, err)
+				http.Error(w, "Invalid target_config", http.StatusBadRequest)
+				return
+			}
+			encoded = string(b)
+		}
+		updates["target_config"] = encoded
+	}
+
+	if h, ok := updates["knovvu_headers"]; ok {
+		headers := map[string]string{}
+		if m, ok := h.(map[string]interface{}); ok {
+			for k, v := range m {
+				headers[k] = fmt.Sprint(v)
+			}
+		} else if h != nil {
+			log.Printf("[PROJECTS][HELPER][ERROR] Invalid knovvu_headers for project id=%d: %v", projectID, h)
+			http.Error(w, "knovvu_headers must be an object", http.StatusBadRequest)
+			return
+		}
+		encoded, err := repo.EncodeKnovvuHeaders(headers)
+		if err != nil {
+			log.Printf("[PROJECTS][HELPER][ERROR] Failed to encode knovvu_headers for project id=%d: %v", projectID, err)
+			http.Error(w, "Invalid knovvu_headers", http.StatusBadRequest)
+			return
+		}
+		updates["knovvu_headers"] = encoded
+	}
+
 	err := env.TestRepo.UpdateTest(projectID, updates)
 	if err != nil {
 		log.Printf("[PROJECTS][HELPER][ERROR] Failed to update project id=%d: %v", projectID, err)
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"evaluator/llm"
+	repo "evaluator/repository"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PromptsHandler handles /api/prompts:
+// GET lists the registry (optionally filtered by ?name= and ?kind=), POST creates a prompt version.
+func (env *APIEnv) PromptsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+
+	switch r.Method {
+	case "OPTIONS":
+		w.WriteHeader(http.StatusOK)
+	case "GET":
+		prompts, err := env.PromptRepo.List(r.URL.Query().Get("name"), r.URL.Query().Get("kind"))
+		if err != nil {
+			log.Printf("[PROMPTS][ERROR] Failed to list prompts: %v", err)
+			http.Error(w, "Failed to retrieve prompts: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if prompts == nil {
+			prompts = []repo.Prompt{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(prompts)
+	case "POST":
+		var p repo.Prompt
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			log.Printf("[PROMPTS][ERROR] Failed to decode prompt: %v", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		p.Name, p.Version = strings.TrimSpace(p.Name), strings.TrimSpace(p.Version)
+		if err := validatePrompt(&p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := env.PromptRepo.Create(&p); err != nil {
+			log.Printf("[PROMPTS][ERROR] Failed to create prompt %s: %v", p.Name, err)
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("[PROMPTS] Created %s prompt %s", p.Kind, p.Ref())
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(p)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// PromptHandler handles /api/prompts/{id}: GET returns a prompt version, PUT updates its
+// description and DELETE removes it. Templates are immutable; edits are new versions.
+func (env *APIEnv) PromptHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, OPTIONS")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/prompts/"), "/")
+	promptID, err := strconv.Atoi(idStr)
+	if err != nil {
+		log.Printf("[PROMPTS][ERROR] Invalid prompt ID in path '%s': %v", idStr, err)
+		http.Error(w, "Invalid prompt ID format", http.StatusBadRequest)
+		return
+	}
+	p, err := env.PromptRepo.GetByID(promptID)
+	if err != nil {
+		log.Printf("[PROMPTS][ERROR] Failed to fetch prompt id=%d: %v", promptID, err)
+		http.Error(w, "Failed to retrieve prompt", http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.Error(w, "Prompt not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(p)
+	case "PUT":
+		var update struct {
+			Description *string `json:"description"`
+			Template    *string `json:"template"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if update.Template != nil && *update.Template != p.Template {
+			http.Error(w, "Prompt templates are immutable; create a new version instead", http.StatusConflict)
+			return
+		}
+		if update.Description != nil {
+			if err := env.PromptRepo.UpdateDescription(promptID, *update.Description); err != nil {
+				log.Printf("[PROMPTS][ERROR] Failed to update prompt id=%d: %v", promptID, err)
+				http.Error(w, "Failed to update prompt", http.StatusInternalServerError)
+				return
+			}
+			p.Description = *update.Description
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(p)
+	case "DELETE":
+		if err := env.PromptRepo.Delete(promptID); err != nil {
+			if errors.Is(err, repo.ErrPromptInUse) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			log.Printf("[PROMPTS][ERROR] Failed to delete prompt id=%d: %v", promptID, err)
+			http.Error(w, "Failed to delete prompt", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("[PROMPTS] Deleted prompt %s", p.Ref())
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func validatePrompt(p *repo.Prompt) error {
+	if p.Name == "" || strings.Contains(p.Name, "@") {
+		return fmt.Errorf("name is required and must not contain '@'")
+	}
+	if p.Name == llm.BuiltinPromptName {
+		return fmt.Errorf("%q is reserved for the built-in prompts", llm.BuiltinPromptName)
+	}
+	if strings.Contains(p.Version, "@") {
+		return fmt.Errorf("version must not contain '@'")
+	}
+	if p.Kind != llm.PromptKindSimulator && p.Kind != llm.PromptKindJudge {
+		return fmt.Errorf("kind must be %q or %q", llm.PromptKindSimulator, llm.PromptKindJudge)
+	}
+	if strings.TrimSpace(p.Template) == "" {
+		return fmt.Errorf("template is required")
+	}
+	return nil
+}
+
+// resolvePrompt returns the registry prompt of the given kind named by ref. An empty ref,
+// or a reference to the built-in version, returns the compiled-in prompt.
+func (env *APIEnv) resolvePrompt(ref, kind string) (*repo.Prompt, error) {
+	if ref == "" {
+		ref = repo.PromptRef(llm.BuiltinPromptName, llm.BuiltinPromptVersion)
+	}
+	name, version, err := repo.ParsePromptRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	if name == llm.BuiltinPromptName {
+		if version != llm.BuiltinPromptVersion {
+			return nil, fmt.Errorf("unknown built-in prompt version %s", ref)
+		}
+		template := llm.SystemPrompt
+		if kind == llm.PromptKindJudge {
+			template = llm.JudgePrompt
+		}
+		return &repo.Prompt{Name: name, Kind: kind, Version: version, Template: template}, nil
+	}
+	p, err := env.PromptRepo.GetVersion(name, version)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("prompt %s not found", ref)
+	}
+	if p.Kind != kind {
+		return nil, fmt.Errorf("prompt %s is a %s prompt, not a %s prompt", ref, p.Kind, kind)
+	}
+	return p, nil
+}
@@ -0,0 +1,349 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"evaluator/agent"
+	"evaluator/cassette"
+	"evaluator/knovvu"
+	"evaluator/llm"
+	repo "evaluator/repository"
+	"evaluator/target"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// runRequest is the optional JSON body accepted by the run endpoints.
+type runRequest struct {
+	LLMProvider       string           `json:"llm_provider,omitempty"` // Overrides the project's simulator provider
+	LLMModel          string           `json:"llm_model,omitempty"`    // Overrides the project's simulator model
+	LLMOptions        llm.CallOptions  `json:"llm_options"`
+	JudgeProvider     string           `json:"judge_provider,omitempty"` // Judge provider; empty reuses the simulator
+	JudgeModel        string           `json:"judge_model,omitempty"`
+	JudgeOptions      *llm.CallOptions `json:"judge_options,omitempty"`       // nil reuses llm_options
+	JudgePanel        []panelMember    `json:"judge_panel,omitempty"`         // Replaces the single judge when set
+	PanelMinAgreement float64          `json:"panel_min_agreement,omitempty"` // 0 means the panel must be unanimous
+	TimeoutSeconds    int              `json:"timeout_seconds,omitempty"`     // Deadline for the whole run; 0 means no limit
+	Cassette          string           `json:"cassette,omitempty"`            // Cassette file name under CASSETTE_DIR
+	CassetteMode      string           `json:"cassette_mode,omitempty"`       // "record" or "replay" (default)
+}
+
+// panelMember configures one entry of a judge panel. Samples > 1 asks the same model repeatedly.
+type panelMember struct {
+	Provider string           `json:"provider"`
+	Model    string           `json:"model,omitempty"`
+	Samples  int              `json:"samples,omitempty"`
+	Options  *llm.CallOptions `json:"options,omitempty"` // nil reuses judge_options
+}
+
+// decodeRunRequest reads the optional run configuration. An empty body yields the defaults.
+func decodeRunRequest(r *http.Request) (runRequest, error) {
+	var req runRequest
+	if r.Body == nil {
+		return req, nil
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		return req, err
+	}
+	return req, nil
+}
+
+// runModels is the resolved simulator and judge selection of a run.
+type runModels struct {
+	TesterProvider llm.LLMProvider
+	TesterModel    string
+	JudgeProvider  llm.LLMProvider
+	JudgeModel     string
+	Panel          []panelSeat
+	MinAgreement   float64
+}
+
+// panelSeat is a single resolved judge of a panel.
+type panelSeat struct {
+	Name     string
+	Provider llm.LLMProvider
+	Model    string
+	Options  llm.CallOptions
+}
+
+// resolveModels resolves the simulator and judge for a run. For the simulator the request body
+// wins over the project settings, and a provider override without a model uses that provider's
+// default. Without a judge override the simulator model also judges the run.
+func (rr runRequest) resolveModels(proj *repo.Test) (runModels, error) {
+	var m runModels
+	name, model := proj.LLMProvider, proj.LLMModel
+	if name == "" {
+		name = string(llm.CohereProvider)
+	}
+	provider, err := llm.ParseProvider(name)
+	if err != nil {
+		return m, err
+	}
+	// Providers are compared once parsed, so "OpenAI" doesn't drop the model of an "openai" project.
+	if rr.LLMProvider != "" {
+		override, err := llm.ParseProvider(rr.LLMProvider)
+		if err != nil {
+			return m, err
+		}
+		if override != provider {
+			provider, model = override, ""
+		}
+	}
+	if rr.LLMModel != "" {
+		model = rr.LLMModel
+	}
+	m.TesterProvider, m.TesterModel = provider, model
+
+	m.JudgeProvider, m.JudgeModel = provider, model
+	if rr.JudgeProvider != "" {
+		if m.JudgeProvider, err = llm.ParseProvider(rr.JudgeProvider); err != nil {
+			return m, fmt.Errorf("judge: %w", err)
+		}
+		m.JudgeModel = ""
+	}
+	if rr.JudgeModel != "" {
+		m.JudgeModel = rr.JudgeModel
+	}
+
+	for _, pm := range rr.JudgePanel {
+		provider, err := llm.ParseProvider(pm.Provider)
+		if err != nil {
+			return m, fmt.Errorf("judge panel: %w", err)
+		}
+		opts := rr.judgeOptions()
+		if pm.Options != nil {
+			opts = *pm.Options
+		}
+		samples := max(pm.Samples, 1)
+		for i := 1; i <= samples; i++ {
+			name := llm.ModelID(provider, pm.Model)
+			if samples > 1 {
+				name = fmt.Sprintf("%s#%d", name, i)
+			}
+			m.Panel = append(m.Panel, panelSeat{Name: name, Provider: provider, Model: pm.Model, Options: opts})
+		}
+	}
+	if rr.PanelMinAgreement < 0 || rr.PanelMinAgreement > 1 {
+		return m, fmt.Errorf("panel_min_agreement must be between 0 and 1")
+	}
+	m.MinAgreement = rr.PanelMinAgreement
+	return m, nil
+}
+
+// judgeOptions returns the call options for the judge, defaulting to the simulator's.
+func (rr runRequest) judgeOptions() llm.CallOptions {
+	if rr.JudgeOptions != nil {
+		return *rr.JudgeOptions
+	}
+	return rr.LLMOptions
+}
+
+// judgeName identifies the judge of a run. A panel is named by the comma-separated list of its judges.
+func (m runModels) judgeName() string {
+	if len(m.Panel) == 0 {
+		return llm.ModelID(m.JudgeProvider, m.JudgeModel)
+	}
+	names := make([]string, len(m.Panel))
+	for i, seat := range m.Panel {
+		names[i] = seat.Name
+	}
+	return strings.Join(names, ",")
+}
+
+// offline replaces every simulator and judge with the mock LLM.
+func (m runModels) offline() runModels {
+	m.TesterProvider, m.TesterModel = llm.MockProvider, ""
+	m.JudgeProvider, m.JudgeModel = llm.MockProvider, ""
+	panel := make([]panelSeat, len(m.Panel))
+	for i, seat := range m.Panel {
+		seat.Provider, seat.Model = llm.MockProvider, ""
+		seat.Name = fmt.Sprintf("%s#%d", llm.ModelID(llm.MockProvider, ""), i+1)
+		panel[i] = seat
+	}
+	m.Panel = panel
+	return m
+}
+
+// runMetadata returns the model identities recorded on the runs row.
+func (m runModels) runMetadata() map[string]interface{} {
+	return map[string]interface{}{
+		"tester_model": llm.ModelID(m.TesterProvider, m.TesterModel),
+		"judge_model":  m.judgeName(),
+	}
+}
+
+// runClients holds the LLM clients of a run, ready to be attached to an agent.
+type runClients struct {
+	Tester     llm.LLM
+	TesterOpts llm.CallOptions
+	Judge      llm.LLM
+	JudgeOpts  llm.CallOptions
+	JudgePanel *llm.JudgePanel
+}
+
+// clients creates the simulator LLM client and either the judge or, when configured, the
+// judge panel clients.
+func (m runModels) clients(rr runRequest) (*runClients, error) {
+	c := &runClients{TesterOpts: rr.LLMOptions, JudgeOpts: rr.judgeOptions()}
+	var err error
+	if c.Tester, err = llm.NewLLMClient(m.TesterProvider, m.TesterModel); err != nil {
+		return nil, err
+	}
+	if len(m.Panel) == 0 {
+		if c.Judge, err = llm.NewLLMClient(m.JudgeProvider, m.JudgeModel); err != nil {
+			return nil, fmt.Errorf("judge: %w", err)
+		}
+	} else {
+		c.JudgePanel = &llm.JudgePanel{MinAgreement: m.MinAgreement}
+		for _, seat := range m.Panel {
+			client, err := llm.NewLLMClient(seat.Provider, seat.Model)
+			if err != nil {
+				return nil, fmt.Errorf("judge panel %s: %w", seat.Name, err)
+			}
+			c.JudgePanel.Judges = append(c.JudgePanel.Judges, llm.PanelJudge{Name: seat.Name, LLM: client, Options: seat.Options})
+		}
+	}
+	return c, nil
+}
+
+// apply attaches the clients to an agent.
+func (c *runClients) apply(a *agent.Agent) {
+	a.LLM, a.LLMOptions = c.Tester, c.TesterOpts
+	a.Judge, a.JudgeOptions = c.Judge, c.JudgeOpts
+	a.JudgePanel = c.JudgePanel
+}
+
+// runPrompts is the resolved simulator and judge prompt versions of a run.
+type runPrompts struct {
+	Simulator *repo.Prompt
+	Judge     *repo.Prompt
+}
+
+// resolvePrompts loads the prompt versions selected by the project.
+func (env *APIEnv) resolvePrompts(proj *repo.Test) (runPrompts, error) {
+	var p runPrompts
+	var err error
+	if p.Simulator, err = env.resolvePrompt(proj.SimulatorPrompt, llm.PromptKindSimulator); err != nil {
+		return p, fmt.Errorf("simulator prompt: %w", err)
+	}
+	if p.Judge, err = env.resolvePrompt(proj.JudgePrompt, llm.PromptKindJudge); err != nil {
+		return p, fmt.Errorf("judge prompt: %w", err)
+	}
+	return p, nil
+}
+
+// record returns the prompt versions as stored in runs.prompt.
+func (p runPrompts) record() string {
+	b, _ := json.Marshal(map[string]string{"simulator": p.Simulator.Ref(), "judge": p.Judge.Ref()})
+	return string(b)
+}
+
+// apply sets the prompts on an agent.
+func (p runPrompts) apply(a *agent.Agent) {
+	a.SimulatorPrompt, a.PromptVersion = p.Simulator.Template, p.Simulator.Ref()
+	a.JudgePrompt = p.Judge.Template
+}
+
+// knovvuClient creates the client for the project's VA from its stored settings.
+func knovvuClient(proj *repo.Test) *knovvu.Client {
+	return knovvu.NewClient(knovvu.Config{
+		Tenant:       proj.TenantID,
+		Region:       proj.KnovvuRegion,
+		BaseURL:      proj.KnovvuBaseURL,
+		IdentityURL:  proj.KnovvuIdentityURL,
+		ChannelID:    proj.KnovvuChannel,
+		ResponseType: proj.KnovvuResponseType,
+		Headers:      proj.KnovvuHeaders,
+	})
+}
+
+// newTarget creates the adapter for the project's bot.
+func newTarget(proj *repo.Test) (target.Target, error) {
+	targetType, err := target.ParseTargetType(proj.TargetType)
+	if err != nil {
+		return nil, err
+	}
+	switch targetType {
+	case target.KnovvuTarget:
+		return target.NewKnovvu(knovvuClient(proj), proj.Name), nil
+	case target.WebhookTarget:
+		cfg, err := target.ParseWebhookConfig(proj.TargetConfig)
+		if err != nil {
+			return nil, err
+		}
+		return target.NewWebhook(cfg)
+	case target.OpenAITarget:
+		cfg, err := target.ParseOpenAIConfig(proj.TargetConfig)
+		if err != nil {
+			return nil, err
+		}
+		return target.NewOpenAI(cfg)
+	case target.DirectLineTarget:
+		cfg, err := target.ParseDirectLineConfig(proj.TargetConfig)
+		if err != nil {
+			return nil, err
+		}
+		return target.NewDirectLine(cfg)
+	case target.MockTarget:
+		cfg, err := target.ParseMockConfig(proj.TargetConfig)
+		if err != nil {
+			return nil, err
+		}
+		return target.NewMock(cfg)
+	}
+	return nil, fmt.Errorf("unsupported target type: %s", targetType)
+}
+
+// runTarget creates the target of a run. In offline mode, projects that don't already use
+// the mock target are run against the default mock dialog.
+func (env *APIEnv) runTarget(proj *repo.Test) (target.Target, error) {
+	if env.Offline && proj.TargetType != string(target.MockTarget) {
+		return target.NewMock(target.MockConfig{})
+	}
+	return newTarget(proj)
+}
+
+// DefaultCassetteDir is where run cassettes are stored when CASSETTE_DIR is not set.
+const DefaultCassetteDir = "./cassettes"
+
+// openCassette opens the cassette named in the request, or returns nil when none is set.
+// Names are plain file names inside CASSETTE_DIR; ".json" is appended when missing.
+func (rr runRequest) openCassette() (*cassette.Cassette, error) {
+	if rr.Cassette == "" {
+		return nil, nil
+	}
+	mode, err := cassette.ParseMode(rr.CassetteMode)
+	if err != nil {
+		return nil, err
+	}
+	name := rr.Cassette
+	if filepath.Base(name) != name || name == "." || name == ".." {
+		return nil, fmt.Errorf("invalid cassette name: %s", rr.Cassette)
+	}
+	if filepath.Ext(name) == "" {
+		name += ".json"
+	}
+	dir := os.Getenv("CASSETTE_DIR")
+	if dir == "" {
+		dir = DefaultCassetteDir
+	}
+	return cassette.Open(filepath.Join(dir, name), mode)
+}
+
+// runContext creates the cancellable context for a run, applying the configured deadline.
+// When c is set, every LLM and target call of the run is recorded to or replayed from it.
+func (rr runRequest) runContext(c *cassette.Cassette) (context.Context, context.CancelFunc) {
+	ctx := context.Background()
+	if c != nil {
+		ctx = cassette.WithCassette(ctx, c)
+	}
+	if rr.TimeoutSeconds > 0 {
+		return context.WithTimeout(ctx, time.Duration(rr.TimeoutSeconds)*time.Second)
+	}
+	return context.WithCancel(ctx)
+}
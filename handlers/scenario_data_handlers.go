@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	repo "evaluator/repository"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxDataRows bounds a scenario's data table, as every row becomes a run.
+const maxDataRows = 100
+
+// templateVariable matches a {{variable}} in a scenario's description or expected output.
+var templateVariable = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// scenarioVariables lists the variables a scenario's templates use, sorted and without duplicates.
+func scenarioVariables(sc *repo.Scenario) []string {
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, text := range []string{sc.Description, sc.ExpectedOutput} {
+		for _, m := range templateVariable.FindAllStringSubmatch(text, -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				names = append(names, m[1])
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// renderTemplate replaces the variables of text with their values.
+func renderTemplate(text string, values map[string]string) string {
+	return templateVariable.ReplaceAllStringFunc(text, func(m string) string {
+		return values[templateVariable.FindStringSubmatch(m)[1]]
+	})
+}
+
+// scenarioCase is one concrete case of a scenario: the scenario rendered with one row of its
+// data table, or the scenario itself when it has none.
+type scenarioCase struct {
+	Row       int // 1-based row of the data table; 0 without a data table
+	Variables map[string]string
+	Scenario  *repo.Scenario
+}
+
+// variablesJSON is the case's values as stored with its run; empty without a data table.
+func (c scenarioCase) variablesJSON() string {
+	if c.Row == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(c.Variables)
+	return string(b)
+}
+
+// scenarioCases expands a scenario into one case per data table row. A scenario that uses
+// variables can't run without a data table, nor one without variables with a table.
+func scenarioCases(sc *repo.Scenario) ([]scenarioCase, error) {
+	variables := scenarioVariables(sc)
+	if len(sc.DataTable) == 0 {
+		if len(variables) > 0 {
+			return nil, fmt.Errorf("scenario %s uses variables %s but has no data table", sc.ID, strings.Join(variables, ", "))
+		}
+		return []scenarioCase{{Scenario: sc}}, nil
+	}
+	if err := validateDataTable(variables, sc.DataTable); err != nil {
+		return nil, fmt.Errorf("scenario %s: %w", sc.ID, err)
+	}
+	cases := make([]scenarioCase, len(sc.DataTable))
+	for i, row := range sc.DataTable {
+		rendered := *sc
+		rendered.Description = renderTemplate(sc.Description, row)
+		rendered.ExpectedOutput = renderTemplate(sc.ExpectedOutput, row)
+		cases[i] = scenarioCase{Row: i + 1, Variables: row, Scenario: &rendered}
+	}
+	return cases, nil
+}
+
+// validateDataTable checks that every row gives a value to each of the variables. A table is
+// rejected when the scenario has no variables, as it would only repeat the same case.
+func validateDataTable(variables []string, table []map[string]string) error {
+	if len(table) > 0 && len(variables) == 0 {
+		return fmt.Errorf("data table given but the scenario uses no {{variables}}")
+	}
+	if len(table) > maxDataRows {
+		return fmt.Errorf("data table has %d rows, at most %d are allowed", len(table), maxDataRows)
+	}
+	for i, row := range table {
+		for name := range row {
+			if !variableName.MatchString(name) {
+				return fmt.Errorf("row %d: invalid variable name %q", i+1, name)
+			}
+		}
+		for _, name := range variables {
+			if _, ok := row[name]; !ok {
+				return fmt.Errorf("row %d has no value for {{%s}}", i+1, name)
+			}
+		}
+	}
+	return nil
+}
+
+// decodeDataTable reads data table rows from JSON objects. Values may be strings, numbers or
+// booleans; numbers keep their exact text, so account numbers aren't rounded.
+func decodeDataTable(rows []map[string]json.RawMessage) ([]map[string]string, error) {
+	table := make([]map[string]string, len(rows))
+	for i, row := range rows {
+		table[i] = make(map[string]string, len(row))
+		for name, raw := range row {
+			raw = bytes.TrimSpace(raw)
+			var value string
+			switch {
+			case len(raw) > 0 && raw[0] == '"':
+				if err := json.Unmarshal(raw, &value); err != nil {
+					return nil, fmt.Errorf("row %d: invalid value of %q", i+1, name)
+				}
+			case len(raw) > 0 && (raw[0] == '-' || raw[0] >= '0' && raw[0] <= '9'), string(raw) == "true", string(raw) == "false":
+				value = string(raw)
+			default:
+				return nil, fmt.Errorf("row %d: %q must be a string, number or boolean", i+1, name)
+			}
+			table[i][name] = value
+		}
+	}
+	return table, nil
+}
+
+// ScenarioDataHandler handles /scenarios/{id}/data: GET returns the scenario's variables and
+// data table, PUT replaces the table with {"rows": [{"variable": "value", ...}, ...]}. An empty
+// table makes the scenario run once, as written.
+func (env *APIEnv) ScenarioDataHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, OPTIONS")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/scenarios/"), "/")
+	scenarioID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid scenario ID format", http.StatusBadRequest)
+		return
+	}
+	scenario, err := env.ScenarioRepo.GetScenarioByID(scenarioID)
+	if err != nil || scenario == nil {
+		http.Error(w, "Scenario not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+	case "PUT":
+		var body struct {
+			Rows []map[string]json.RawMessage `json:"rows"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		table, err := decodeDataTable(body.Rows)
+		if err == nil {
+			err = validateDataTable(scenarioVariables(scenario), table)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if scenario, err = env.ScenarioRepo.UpdateScenario(scenarioID, map[string]interface{}{"data_table": table}); err != nil {
+			log.Printf("[SCENARIO-DATA][ERROR] Failed to set data table of scenario_id=%d: %v", scenarioID, err)
+			http.Error(w, "Failed to update scenario", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("[SCENARIO-DATA] Scenario_id=%d now has %d data rows", scenarioID, len(table))
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":        scenario.ID,
+		"variables": scenarioVariables(scenario),
+		"rows":      scenario.DataTable,
+	})
+}
+
+// scenarioRun is one execution of a scenario in a run request: a case of its data table played
+// by one persona.
+type scenarioRun struct {
+	Case    scenarioCase
+	Persona *repo.Persona // nil for the generic tester
+}
+
+// scenarioRuns expands a scenario into its executions: every case of its data table, each
+// played by every persona of the scenario or project.
+func (env *APIEnv) scenarioRuns(proj *repo.Test, sc *repo.Scenario) ([]scenarioRun, error) {
+	cases, err := scenarioCases(sc)
+	if err != nil {
+		return nil, err
+	}
+	personas, err := env.runPersonas(proj, sc)
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]scenarioRun, 0, len(cases)*len(personas))
+	for _, c := range cases {
+		for _, p := range personas {
+			runs = append(runs, scenarioRun{Case: c, Persona: p})
+		}
+	}
+	return runs, nil
+}
+
+// tag records the persona, language and data row of the execution in a run's metadata.
+func (sr scenarioRun) tag(proj *repo.Test, metadata map[string]interface{}) {
+	metadata["persona"] = personaName(sr.Persona)
+	metadata["language"] = runLanguage(proj, sr.Case.Scenario, sr.Persona)
+	metadata["data_row"] = sr.Case.Row
+	metadata["variables"] = sr.Case.variablesJSON()
+}
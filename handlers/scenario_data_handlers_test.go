@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	repo "evaluator/repository"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScenarioVariables(t *testing.T) {
+	sc := &repo.Scenario{
+		Description:    "Send {{ amount }} to {{account}}, then {{account}} again; {{ 1bad }} and {{}} aren't variables",
+		ExpectedOutput: "The VA confirms {{amount}} for {{_note}}",
+	}
+	if got, want := scenarioVariables(sc), []string{"_note", "account", "amount"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("scenarioVariables() = %v, want %v", got, want)
+	}
+	if got := scenarioVariables(&repo.Scenario{Description: "Say hello"}); len(got) != 0 {
+		t.Errorf("scenarioVariables() = %v, want none", got)
+	}
+}
+
+func TestDecodeDataTable(t *testing.T) {
+	decode := func(s string) ([]map[string]string, error) {
+		var rows []map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(s), &rows); err != nil {
+			t.Fatal(err)
+		}
+		return decodeDataTable(rows)
+	}
+
+	// Numbers keep their exact text: no float rounding, exponent or trailing zeros lost
+	got, err := decode(`[{"iban": 12345678901234567890123, "amount": -12.50, "rate": 1e3, "vip": true, "city": "İzmir", "quote": "say \"hi\""}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]string{{"iban": "12345678901234567890123", "amount": "-12.50", "rate": "1e3", "vip": "true", "city": "İzmir", "quote": `say "hi"`}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeDataTable() = %v, want %v", got, want)
+	}
+
+	for _, rows := range []string{
+		`[{"city": "Ankara"}, {"city": null}]`,
+		`[{"city": {"name": "Ankara"}}]`,
+		`[{"city": ["Ankara"]}]`,
+	} {
+		_, err := decode(rows)
+		if err == nil {
+			t.Errorf("decodeDataTable(%s) succeeded, want an error", rows)
+		} else if strings.Contains(rows, "null") && !strings.HasPrefix(err.Error(), "row 2:") {
+			t.Errorf("decodeDataTable(%s) error = %v, want it to name row 2", rows, err)
+		}
+	}
+}
+
+func TestScenarioCases(t *testing.T) {
+	table := []map[string]string{
+		{"account": "4021", "city": "Ankara"},
+		{"account": "{{city}}", "city": "Izmir", "unused": "x"},
+	}
+	sc := &repo.Scenario{
+		ID:             "2",
+		Description:    "Ask for the balance of {{account}} from {{ city }}",
+		ExpectedOutput: "The VA reports the balance of {{account}}",
+		DataTable:      table,
+	}
+	cases, err := scenarioCases(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) != 2 {
+		t.Fatalf("scenarioCases() returned %d cases, want 2", len(cases))
+	}
+	if c := cases[0]; c.Row != 1 || c.Scenario.Description != "Ask for the balance of 4021 from Ankara" || c.Scenario.ExpectedOutput != "The VA reports the balance of 4021" {
+		t.Errorf("case 1 = %+v", c.Scenario)
+	}
+	// Values are inserted as they are, never rendered again
+	if c := cases[1]; c.Row != 2 || c.Scenario.Description != "Ask for the balance of {{city}} from Izmir" {
+		t.Errorf("case 2 = %+v", c.Scenario)
+	}
+	if got := cases[1].variablesJSON(); got != `{"account":"{{city}}","city":"Izmir","unused":"x"}` {
+		t.Errorf("variablesJSON() = %s", got)
+	}
+	// Rendering works on copies; the stored scenario keeps its templates
+	if sc.Description != "Ask for the balance of {{account}} from {{ city }}" || cases[0].Scenario == sc {
+		t.Errorf("scenarioCases() changed the scenario: %+v", sc)
+	}
+
+	plain := &repo.Scenario{ID: "1", Description: "Say hello", ExpectedOutput: "The VA greets"}
+	cases, err = scenarioCases(plain)
+	if err != nil || len(cases) != 1 || cases[0].Row != 0 || cases[0].Scenario != plain || cases[0].variablesJSON() != "" {
+		t.Errorf("scenarioCases() without a table = %+v, %v, want the scenario itself", cases, err)
+	}
+
+	tooMany := make([]map[string]string, maxDataRows+1)
+	for i := range tooMany {
+		tooMany[i] = map[string]string{"account": "1"}
+	}
+	for name, sc := range map[string]*repo.Scenario{
+		"variables without a table": {ID: "3", Description: "Ask for {{account}}"},
+		"table without variables":   {ID: "4", Description: "Say hello", DataTable: []map[string]string{{"account": "4021"}}},
+		"row missing a value":       {ID: "5", Description: "Ask for {{account}}", DataTable: []map[string]string{{"account": "1"}, {"city": "Izmir"}}},
+		"invalid variable name":     {ID: "6", Description: "Ask for {{account}}", DataTable: []map[string]string{{"account": "1", "bad name": "x"}}},
+		"too many rows":             {ID: "7", Description: "Ask for {{account}}", DataTable: tooMany},
+	} {
+		if _, err := scenarioCases(sc); err == nil {
+			t.Errorf("%s: scenarioCases() succeeded, want an error", name)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"evaluator/llm"
 	repo "evaluator/repository" // Ensure this import path is correct
 	"log"
 	"net/http"
@@ -31,8 +32,12 @@ func (env *APIEnv) UploadScenariosHandler(w http.ResponseWriter, r *http.Request
 	type ScenarioUploadPayload struct {
 		TestID    string `json:"test_id"`
 		Scenarios []struct {
-			Description    string `json:"description"`
-			ExpectedOutput string `json:"expected_output"`
+			Description    string   `json:"description"`
+			ExpectedOutput string   `json:"expected_output"`
+			Personas       []string `json:"personas"` // Empty uses the project's personas
+			Language       string   `json:"language"` // Empty uses the persona's or the project's language
+			// Values of the {{variables}} in description and expected_output, one row per case
+			Data []map[string]json.RawMessage `json:"data"`
 		} `json:"scenarios"`
 	}
 
@@ -64,7 +69,26 @@ func (env *APIEnv) UploadScenariosHandler(w http.ResponseWriter, r *http.Request
 	for _, s := range payload.Scenarios {
 		log.Printf("[UPLOAD-SCENARIOS] Creating scenario for test_id=%d: description=\"%s\"", testID, s.Description)
 		// Using env.ScenarioRepo now
-		sc, err := env.ScenarioRepo.CreateScenario(testID, s.Description, s.ExpectedOutput)
+		personas, err := env.validatePersonaNames(s.Personas)
+		var language string
+		if err == nil {
+			language, err = llm.NormalizeLanguage(s.Language)
+		}
+		var table []map[string]string
+		if err == nil {
+			table, err = decodeDataTable(s.Data)
+		}
+		if err == nil && len(table) > 0 {
+			err = validateDataTable(scenarioVariables(&repo.Scenario{Description: s.Description, ExpectedOutput: s.ExpectedOutput}), table)
+		}
+		var sc *repo.Scenario
+		if err == nil {
+			sc, err = env.ScenarioRepo.CreateScenario(testID, s.Description, s.ExpectedOutput)
+		}
+		if err == nil && (len(personas) > 0 || language != "" || len(table) > 0) {
+			id, _ := strconv.Atoi(sc.ID)
+			sc, err = env.ScenarioRepo.UpdateScenario(id, map[string]interface{}{"personas": personas, "language": language, "data_table": table})
+		}
 		if err != nil {
 			log.Printf("[UPLOAD-SCENARIOS] Error creating scenario for description=\"%s\": %v", s.Description, err)
 			results = append(results, map[string]any{
@@ -148,6 +172,10 @@ func (env *APIEnv) GetScenariosByTestIDHandler(w http.ResponseWriter, r *http.Re
 			"description":     s.Description,
 			"expected_output": s.ExpectedOutput,
 			"status":          s.Status,
+			"personas":        s.Personas,
+			"language":        s.Language,
+			"variables":       scenarioVariables(&s),
+			"data_table":      s.DataTable,
 		})
 	}
 
@@ -185,18 +213,23 @@ func (env *APIEnv) StopScenarioHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = env.ScenarioRepo.UpdateScenario(scenarioID, map[string]interface{}{"status": "Error"})
-	if err != nil {
-		log.Printf("[SCENARIO-STOP][ERROR] Failed to update scenario status to Error for id=%d: %v", scenarioID, err)
-		http.Error(w, "Failed to update scenario status", http.StatusInternalServerError)
+	// Cancel the in-flight run(s) of the scenario, including its cases in a project run or
+	// experiment; the run goroutine persists the partial transcript and marks both the runs
+	// and the scenario as cancelled.
+	cancelled, found := env.Runs.CancelScenario(scenarioID)
+	if !found {
+		// Nothing is running in this process, so the scenario's status is left as it is
+		log.Printf("[SCENARIO-STOP][WARN] No active run for scenario_id=%d", scenarioID)
+		http.Error(w, "No active run found for this scenario", http.StatusNotFound)
 		return
 	}
-
+	log.Printf("[SCENARIO-STOP] Cancelled runs %v for scenario_id=%d", cancelled, scenarioID)
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"scenario_id": scenarioID,
-		"status":      "Error",
-		"message":     "Scenario stopped and marked as Error",
+		"run_ids":     cancelled,
+		"status":      "Cancelled",
+		"message":     "Scenario run is being cancelled",
 	})
 }
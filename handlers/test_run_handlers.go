@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"evaluator/agent"
 	"evaluator/llm"
 	repo "evaluator/repository" // Ensure this import path is correct
+	"evaluator/target"
 	"log"
 	"net/http"
 	"strconv"
@@ -18,126 +21,246 @@ func (env *APIEnv) handleRunProjectTest(w http.ResponseWriter, r *http.Request,
 	// or a middleware. If called directly, ensure CORS is handled.
 	log.Printf("[PROJ-RUN][HELPER] Test run initiated for project_id=%d", projectID)
 
-	// Using env.TestRunRepo now
-	//TODO Project ID must by scenario ID
-	runID, err := env.TestRunRepo.CreateTestRun(projectID, nil) // Assuming nil is acceptable for ScenariosJson initially
+	runCfg, err := decodeRunRequest(r)
 	if err != nil {
-		log.Printf("[PROJ-RUN][BACKEND][ERROR] Failed to create test run entry for project_id=%d: %v", projectID, err)
-		http.Error(w, "Failed to create test run entry", http.StatusInternalServerError)
+		log.Printf("[PROJ-RUN][HELPER][ERROR] Invalid run configuration for project_id=%d: %v", projectID, err)
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
 		return
 	}
-	log.Printf("[PROJ-RUN][BACKEND] Test run entry created: project_id=%d, run_id=%d. Starting background process.", projectID, runID)
 
-	go func(currentProjectID, currentRunID int) {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("[PROJ-RUN][GOROUTINE][PANIC] Recovered from panic: %v", r)
-				// Mark all scenarios in this run as Error
-				scenarios, err := env.ScenarioRepo.GetScenariosByTestID(currentProjectID)
-				if err == nil {
-					for _, sc := range scenarios {
-						if idInt, err := strconv.Atoi(sc.ID); err == nil {
-							env.ScenarioRepo.UpdateScenario(idInt, map[string]interface{}{"status": "Error"})
-						}
-					}
-				}
-				env.TestRunRepo.UpdateTestRunStatus(currentRunID, "failed", nil, nil)
-			}
-		}()
-		log.Printf("[PROJ-RUN][GOROUTINE] Running test in background: project_id=%d, run_id=%d", currentProjectID, currentRunID)
-		env.TestRunRepo.UpdateTestRunStatus(currentRunID, "running", nil, nil)
+	testProject, err := env.TestRepo.GetTestByID(projectID)
+	if err != nil || testProject == nil {
+		log.Printf("[PROJ-RUN][HELPER][ERROR] Failed to fetch test/project details for project_id=%d: %v", projectID, err)
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	}
+	models, err := runCfg.resolveModels(testProject)
+	if err != nil {
+		log.Printf("[PROJ-RUN][HELPER][ERROR] Invalid LLM selection for project_id=%d: %v", projectID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if env.Offline {
+		models = models.offline()
+	}
+	tape, err := runCfg.openCassette()
+	if err != nil {
+		log.Printf("[PROJ-RUN][HELPER][ERROR] Failed to open cassette for project_id=%d: %v", projectID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	prompts, err := env.resolvePrompts(testProject)
+	if err != nil {
+		log.Printf("[PROJ-RUN][HELPER][ERROR] Invalid prompt selection for project_id=%d: %v", projectID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	metadata := models.runMetadata()
+	metadata["prompt"] = prompts.record()
 
-		// --- Full Agent Logic for all scenarios in a project ---
-		scenarios, err := env.ScenarioRepo.GetScenariosByTestID(currentProjectID)
+	scenarios, err := env.ScenarioRepo.GetScenariosByTestID(projectID)
+	if err != nil {
+		log.Printf("[PROJ-RUN][HELPER][ERROR] Failed to fetch scenarios for project_id=%d: %v", projectID, err)
+		http.Error(w, "Failed to retrieve scenarios", http.StatusInternalServerError)
+		return
+	}
+	if len(scenarios) == 0 {
+		http.Error(w, "Project has no scenarios", http.StatusBadRequest)
+		return
+	}
+
+	// Every case of every scenario gets its own run. The runs are created before responding so
+	// their IDs can be returned, and registered first so a stop request can never miss them.
+	ctx, cancel := runCfg.runContext(tape)
+	handle := env.Runs.Register(projectID, 0, cancel)
+	planned := make([][]projectRun, len(scenarios))
+	runIDs := []int{}
+	for i := range scenarios {
+		sc := &scenarios[i]
+		idInt, err := strconv.Atoi(sc.ID)
 		if err != nil {
-			log.Printf("[PROJ-RUN][GOROUTINE][ERROR] Failed to fetch scenarios for project_id=%d, run_id=%d: %v", currentProjectID, currentRunID, err)
-			env.TestRunRepo.UpdateTestRunStatus(currentRunID, "Error", nil, nil)
-			// Potentially update individual scenarios to "Error" or "Skipped"
-			return
+			continue
+		}
+		executions, err := env.scenarioRuns(testProject, sc)
+		if err != nil {
+			log.Printf("[PROJ-RUN][HELPER][ERROR] Failed to expand scenario_id=%s: %v", sc.ID, err)
+			env.ScenarioRepo.UpdateScenario(idInt, map[string]interface{}{"status": "Error"})
+			continue
 		}
+		for _, execution := range executions {
+			execution.tag(testProject, metadata)
+			runID, err := env.TestRunRepo.CreateTestRun(idInt, metadata)
+			if err != nil {
+				log.Printf("[PROJ-RUN][HELPER][ERROR] Failed to create test run entry for scenario_id=%s, data_row=%d, persona=%q: %v", sc.ID, execution.Case.Row, personaName(execution.Persona), err)
+			} else {
+				env.Runs.AddRun(handle, idInt, runID)
+				runIDs = append(runIDs, runID)
+			}
+			planned[i] = append(planned[i], projectRun{RunID: runID, Execution: execution})
+		}
+	}
 
-		if len(scenarios) == 0 {
-			log.Printf("[PROJ-RUN][GOROUTINE][WARN] No scenarios found for project_id=%d, run_id=%d. Marking as completed.", currentProjectID, currentRunID)
-			env.TestRunRepo.UpdateTestRunStatus(currentRunID, "Error", nil, nil) // Or a different status like "no_scenarios"
-			return
+	go func(currentProjectID int) {
+		defer env.Runs.Unregister(handle)
+		defer cancel()
+		finished := make(map[int]bool)
+		markScenarios := func(status string) {
+			for _, sc := range scenarios {
+				if idInt, err := strconv.Atoi(sc.ID); err == nil {
+					env.ScenarioRepo.UpdateScenario(idInt, map[string]interface{}{"status": status})
+				}
+			}
 		}
+		// markRuns ends every run that hasn't finished yet with the run status and verdict
+		markRuns := func(status, verdict string) {
+			for _, runID := range runIDs {
+				if !finished[runID] {
+					env.TestRunRepo.UpdateTestRunStatus(runID, status, &verdict, nil)
+				}
+			}
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("[PROJ-RUN][GOROUTINE][PANIC] Recovered from panic: %v", r)
+				// Mark all scenarios and unfinished runs of this project run as failed
+				markScenarios("Error")
+				markRuns("failed", "Error")
+			}
+		}()
+		log.Printf("[PROJ-RUN][GOROUTINE] Running test in background: project_id=%d, %d scenarios, %d runs", currentProjectID, len(scenarios), len(runIDs))
 
-		testProject, err := env.TestRepo.GetTestByID(currentProjectID)
+		clients, err := models.clients(runCfg)
 		if err != nil {
-			log.Printf("[PROJ-RUN][GOROUTINE][ERROR] Failed to fetch test/project details for project_id=%d: %v", currentProjectID, err)
-			env.TestRunRepo.UpdateTestRunStatus(currentRunID, "Error", nil, nil)
+			log.Printf("[PROJ-RUN][GOROUTINE][ERROR] Failed to create LLM client for project_id=%d: %v", currentProjectID, err)
+			markScenarios("Error")
+			markRuns("failed", "Error")
 			return
 		}
 
-		llmClient, err := llm.NewLLMClient(llm.CohereProvider, llm.CohereModel) // TODO: Make provider/model configurable
+		bot, err := env.runTarget(testProject)
 		if err != nil {
-			log.Printf("[PROJ-RUN][GOROUTINE][ERROR] Failed to create LLM client for run_id=%d: %v", currentRunID, err)
-			env.TestRunRepo.UpdateTestRunStatus(currentRunID, "Error", nil, nil)
+			log.Printf("[PROJ-RUN][GOROUTINE][ERROR] Failed to create target for project_id=%d: %v", currentProjectID, err)
+			markScenarios("Error")
+			markRuns("failed", "Error")
 			return
 		}
-
 		overallSuccess := true
-		for _, sc := range scenarios {
-			log.Printf("[PROJ-RUN][GOROUTINE] Starting agent for scenario_id=%d, run_id=%d", sc.ID, currentRunID)
-			initialState := llm.CurrentState{
-				History:   []llm.HistoryItem{},
-				TurnCount: 0,
-				MaxTurns:  int16(testProject.MaxInteractions), // Use MaxInteractions from the project
-				Fulfilled: false,
+		for i := range scenarios {
+			sc := &scenarios[i]
+			idInt, err := strconv.Atoi(sc.ID)
+			if err != nil || len(planned[i]) == 0 {
+				continue
 			}
-
-			// Agent expects DB connection, pass env.DB
-			testingAgent := agent.NewAgent(testProject.Name, sc.Description, sc.ExpectedOutput, initialState, llmClient, env.DB)
-
-			finalState, finaljudgement, agentErr := testingAgent.Run()
-			currentScenarioStatus := finaljudgement.Judgement
-
-			if agentErr != nil {
-				log.Printf("[PROJ-RUN][GOROUTINE][ERROR] Agent run failed for scenario_id=%d, run_id=%d: %v", sc.ID, currentRunID, agentErr)
-				currentScenarioStatus = "Error"
-			} else if !finalState.Fulfilled {
-				log.Printf("[PROJ-RUN][GOROUTINE][INFO] Agent run completed but not fulfilled for scenario_id=%d, run_id=%d. Turns: %d", sc.ID, currentRunID, finalState.TurnCount)
-				currentScenarioStatus = "Fail"
-				overallSuccess = false
-			} else {
-				log.Printf("[PROJ-RUN][GOROUTINE][INFO] Agent run successful for scenario_id=%d, run_id=%d. Fulfilled: %v, Turns: %d", sc.ID, currentRunID, finalState.Fulfilled, finalState.TurnCount)
-			}
-
-			// Update individual scenario status
-			if idInt, err := strconv.Atoi(sc.ID); err == nil {
-				env.ScenarioRepo.UpdateScenario(idInt, map[string]interface{}{"status": currentScenarioStatus})
-			}
-
-			// Record interactions for this scenario
-			for _, h := range finalState.History {
-				if idInt, err := strconv.Atoi(sc.ID); err == nil {
-					interaction := repo.Interaction{
-						TestRunID:   currentRunID,
-						ScenarioID:  idInt,
-						TurnNumber:  int(h.Turn),
-						UserMessage: h.User,
-						LLMResponse: h.Assistant,
-					}
-					err := env.InteractionRepo.Create(&interaction)
-					if err != nil {
-						log.Printf("[PROJ-RUN][GOROUTINE][ERROR] Failed to record interaction for scenario_id=%d, run_id=%d, turn=%d: %v", sc.ID, currentRunID, h.Turn, err)
-					}
+			// A stop request for this scenario cancels scenarioCtx, and only its remaining cases
+			scenarioCtx, cancelScenario := context.WithCancel(ctx)
+			env.Runs.StartScenario(handle, idInt, cancelScenario)
+			scenarioStatus := ""
+			for _, pr := range planned[i] {
+				if pr.RunID == 0 {
+					scenarioStatus = combineStatus(scenarioStatus, "Error")
+					continue
 				}
+				if scenarioCtx.Err() != nil {
+					// Cases left over by a stop request or the timeout never start
+					verdict := interruptedStatus(scenarioCtx)
+					env.TestRunRepo.UpdateTestRunStatus(pr.RunID, interruptedRunStatus(scenarioCtx), &verdict, nil)
+					finished[pr.RunID] = true
+					scenarioStatus = combineStatus(scenarioStatus, verdict)
+					continue
+				}
+				env.TestRunRepo.UpdateTestRunStatus(pr.RunID, "running", nil, nil)
+				log.Printf("[PROJ-RUN][GOROUTINE] Starting agent for scenario_id=%s, run_id=%d, data_row=%d, persona=%q", sc.ID, pr.RunID, pr.Execution.Case.Row, personaName(pr.Execution.Persona))
+
+				setup := scenarioSetup{Project: testProject, Clients: clients, Prompts: prompts, Target: bot, JudgeName: models.judgeName(), Persona: pr.Execution.Persona, DataRow: pr.Execution.Case.Row}
+				outcome := env.executeScenarioRun(scenarioCtx, pr.RunID, idInt, pr.Execution.Case.Scenario, setup)
+				finished[pr.RunID] = true
+				if outcome.Status != llm.JudgementPass {
+					overallSuccess = false
+				}
+				scenarioStatus = combineStatus(scenarioStatus, outcome.Status)
 			}
-		}
+			env.Runs.EndScenario(handle)
+			cancelScenario()
 
-		finalStatus := "completed"
-		if !overallSuccess {
-			// finalStatus = "completed_with_failures" // Or keep "completed" and rely on scenario statuses
+			// Update individual scenario status: it passes only if every case and persona passed
+			env.ScenarioRepo.UpdateScenario(idInt, map[string]interface{}{"status": scenarioStatus})
 		}
-		env.TestRunRepo.UpdateTestRunStatus(currentRunID, finalStatus, nil, nil)
-		log.Printf("[PROJ-RUN][GOROUTINE] Test run completed: project_id=%d, run_id=%d. Overall success: %t", currentProjectID, currentRunID, overallSuccess)
-
-	}(projectID, runID) // Pass current projectID and newly created runID
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			log.Printf("[PROJ-RUN][GOROUTINE] Test run timed out: project_id=%d, %d runs", currentProjectID, len(runIDs))
+		} else if ctx.Err() != nil {
+			log.Printf("[PROJ-RUN][GOROUTINE] Test run cancelled: project_id=%d, %d runs", currentProjectID, len(runIDs))
+		} else {
+			log.Printf("[PROJ-RUN][GOROUTINE] Test run completed: project_id=%d, %d runs. Overall success: %t", currentProjectID, len(runIDs), overallSuccess)
+		}
+	}(projectID)
 
+	// run_id is the first run, kept for clients that poll a single run
+	response := map[string]interface{}{"project_id": projectID, "scenarios": len(scenarios), "run_ids": runIDs, "status": "started"}
+	if len(runIDs) > 0 {
+		response["run_id"] = runIDs[0]
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusAccepted) // 202 Accepted as the test runs in background
-	json.NewEncoder(w).Encode(map[string]interface{}{"run_id": runID, "status": "started"})
+	json.NewEncoder(w).Encode(response)
+}
+
+// projectRun is one case of a project run, created before the run starts.
+type projectRun struct {
+	RunID     int // 0 when the runs row couldn't be created
+	Execution scenarioRun
+}
+
+// newInteraction converts a turn of the agent's history into an interaction row,
+// including the simulator's metadata for that turn.
+func newInteraction(runID, scenarioID, dataRow int, persona string, h llm.HistoryItem) repo.Interaction {
+	interaction := repo.Interaction{
+		TestRunID:    runID,
+		ScenarioID:   scenarioID,
+		DataRow:      dataRow,
+		Persona:      persona,
+		TurnNumber:   int(h.Turn),
+		UserMessage:  h.User,
+		LLMResponse:  h.Assistant,
+		VALanguage:   h.VALanguage,
+		VAActivities: h.Activities,
+	}
+	if out := h.Simulator; out != nil {
+		interaction.Fulfilled = out.Fulfilled
+		interaction.Reasoning = out.Reasoning
+		interaction.Strategy = out.Strategy
+		interaction.Confidence = out.Confidence
+		interaction.SafetyCheck = out.SafetyCheck
+		interaction.ErrorLogs = out.ErrorLogs
+		interaction.AdaptationNotes = out.AdaptationNotes
+		interaction.Action = out.Action
+		interaction.SelectedOption = out.SelectedOption
+	}
+	return interaction
+}
+
+// recordJudgments stores the judge output for one scenario of a run: one row per panel member,
+// or a single row named judgeModel when the run has a single judge.
+func (env *APIEnv) recordJudgments(runID, scenarioID, dataRow int, persona, judgeModel string, result *llm.JudgmentResult) {
+	if result == nil {
+		return
+	}
+	panel := result.Panel
+	if len(panel) == 0 {
+		panel = []llm.PanelJudgment{{Judge: judgeModel, Result: result}}
+	}
+	for _, pj := range panel {
+		j := repo.Judgment{TestRunID: runID, ScenarioID: scenarioID, Persona: persona, DataRow: dataRow, JudgeModel: pj.Judge, Error: pj.Error}
+		if pj.Result != nil {
+			j.Judgment = pj.Result.Judgement
+			j.Confidence = pj.Result.Confidence
+			j.EvidenceSummary = pj.Result.EvidenceSummary
+			j.ScenarioCompletionScore = pj.Result.ScenarioCompletionScore
+			j.ConversationQualityScore = pj.Result.ConversationQualityScore
+		}
+		if err := env.JudgmentRepo.Create(&j); err != nil {
+			log.Printf("[RUN][ERROR] Failed to record judgment of %s for scenario_id=%d, run_id=%d: %v", pj.Judge, scenarioID, runID, err)
+		}
+	}
 }
 
 func (env *APIEnv) handleGetProjectTestStatus(w http.ResponseWriter, r *http.Request, projectID int) {
@@ -172,6 +295,26 @@ func (env *APIEnv) handleGetProjectTestStatus(w http.ResponseWriter, r *http.Req
 	})
 }
 
+// handleStopProjectTest cancels every in-flight run of a project.
+// It's called by ProjectDispatchHandler for POST /projects/{id}/stop-test.
+func (env *APIEnv) handleStopProjectTest(w http.ResponseWriter, r *http.Request, projectID int) {
+	cancelled, found := env.Runs.CancelProject(projectID)
+	log.Printf("[PROJ-RUN][INFO] Stop requested for project_id=%d, cancelled runs: %v", projectID, cancelled)
+
+	if !found {
+		http.Error(w, "No active test runs found for this project", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"project_id": projectID,
+		"run_ids":    cancelled,
+		"status":     "cancelling",
+	})
+}
+
 // GetTestRunsByScenarioHandler handles GET /scenarios/{scenarioID}/runs
 // Example route: /scenarios/123/runs?limit=10&offset=0
 func (env *APIEnv) GetTestRunsByScenarioHandler(w http.ResponseWriter, r *http.Request) {
@@ -281,6 +424,13 @@ func (env *APIEnv) ScenarioRunHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	runCfg, err := decodeRunRequest(r)
+	if err != nil {
+		log.Printf("[SCENARIO-RUN][ERROR] Invalid run configuration for scenario_id=%d: %v", scenarioID, err)
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	log.Printf("[SCENARIO-RUN] Initiating async run for scenario_id=%d", scenarioID)
 
 	// Fetch scenario details
@@ -300,12 +450,42 @@ func (env *APIEnv) ScenarioRunHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	testProject, err := env.TestRepo.GetTestByID(testIDInt)
-	if err != nil {
-		log.Printf("[SCENARIO-RUN][ERROR] Could not fetch Test/Project details for test_id=%d: %v", scenario.TestID, err)
+	if err != nil || testProject == nil {
+		log.Printf("[SCENARIO-RUN][ERROR] Could not fetch Test/Project details for test_id=%s: %v", scenario.TestID, err)
 		http.Error(w, "Failed to fetch project details for scenario run", http.StatusInternalServerError)
 		return
 	}
 
+	models, err := runCfg.resolveModels(testProject)
+	if err != nil {
+		log.Printf("[SCENARIO-RUN][ERROR] Invalid LLM selection for scenario_id=%d: %v", scenarioID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if env.Offline {
+		models = models.offline()
+	}
+	tape, err := runCfg.openCassette()
+	if err != nil {
+		log.Printf("[SCENARIO-RUN][ERROR] Failed to open cassette for scenario_id=%d: %v", scenarioID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	prompts, err := env.resolvePrompts(testProject)
+	if err != nil {
+		log.Printf("[SCENARIO-RUN][ERROR] Invalid prompt selection for scenario_id=%d: %v", scenarioID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	executions, err := env.scenarioRuns(testProject, scenario)
+	if err != nil {
+		log.Printf("[SCENARIO-RUN][ERROR] Invalid persona selection or data table for scenario_id=%d: %v", scenarioID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	metadata := models.runMetadata()
+	metadata["prompt"] = prompts.record()
+
 	// STEP 1: Immediately update status to "Running" in DB
 	if _, err := env.ScenarioRepo.UpdateScenario(scenarioID, map[string]interface{}{"status": "Running"}); err != nil {
 		log.Printf("[SCENARIO-RUN][ERROR] Failed to update scenario status to running for id=%d: %v", scenarioID, err)
@@ -313,70 +493,59 @@ func (env *APIEnv) ScenarioRunHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// STEP 2: Start the long-running process in a goroutine
-	go func(sID int, proj *repo.Test, scen *repo.Scenario) {
-		log.Printf("[SCENARIO-RUN][GOROUTINE] Starting agent for scenario_id=%d", sID)
-
-		runID, err := env.TestRunRepo.CreateTestRun(sID, nil)
+	// STEP 2: Start the long-running process in a goroutine. One context and registration cover
+	// every case's run, and the registration happens before the goroutine starts so a stop
+	// request can never miss it.
+	ctx, cancel := runCfg.runContext(tape)
+	handle := env.Runs.Register(testProject.ID, scenarioID, cancel)
+	go func(sID int, proj *repo.Test) {
+		defer env.Runs.Unregister(handle)
+		defer cancel()
+		log.Printf("[SCENARIO-RUN][GOROUTINE] Starting agent for scenario_id=%d with %d case(s)", sID, len(executions))
+
+		clients, err := models.clients(runCfg)
 		if err != nil {
-			log.Printf("[SCENARIO-RUN][GOROUTINE][ERROR] Failed to create test run entry for scenario_id=%d: %v", sID, err)
+			log.Printf("[SCENARIO-RUN][GOROUTINE][ERROR] Failed to create LLM client for scenario_id=%d: %v", sID, err)
 			if _, err := env.ScenarioRepo.UpdateScenario(sID, map[string]interface{}{"status": "Fail"}); err != nil {
 				log.Printf("[SCENARIO-RUN][GOROUTINE][ERROR] Failed to update scenario status to Fail for scenario_id=%d: %v", sID, err)
 			}
 			return
 		}
-		env.TestRunRepo.UpdateTestRunStatus(runID, "running", nil, nil)
 
-		llmClient, err := llm.NewLLMClient(llm.CohereProvider, llm.CohereModel)
+		bot, err := env.runTarget(proj)
 		if err != nil {
-			log.Printf("[SCENARIO-RUN][GOROUTINE][ERROR] Failed to create LLM client for scenario_id=%d: %v", sID, err)
-			env.TestRunRepo.UpdateTestRunStatus(runID, "failed", nil, nil)
+			log.Printf("[SCENARIO-RUN][GOROUTINE][ERROR] Failed to create target for scenario_id=%d: %v", sID, err)
 			if _, err := env.ScenarioRepo.UpdateScenario(sID, map[string]interface{}{"status": "Fail"}); err != nil {
 				log.Printf("[SCENARIO-RUN][GOROUTINE][ERROR] Failed to update scenario status to Fail for scenario_id=%d: %v", sID, err)
 			}
 			return
 		}
 
-		initialState := llm.CurrentState{
-			History:   []llm.HistoryItem{},
-			TurnCount: 0,
-			MaxTurns:  int16(proj.MaxInteractions),
-			Fulfilled: false,
-		}
-		testingAgent := agent.NewAgent(proj.Name, scen.Description, scen.ExpectedOutput, initialState, llmClient, env.DB)
-		finalState, finalJudgement, agentErr := testingAgent.Run()
-
-		runStatus := "completed"
-		scenarioStatus := finalJudgement.Judgement
-		sceanrioReasoning := finalJudgement.EvidenceSummary
-		if agentErr != nil || !finalState.Fulfilled {
-			runStatus = "failed"
-			if agentErr != nil {
-				scenarioStatus = "Fail"
+		status := ""
+		for _, execution := range executions {
+			if ctx.Err() != nil {
+				status = combineStatus(status, interruptedStatus(ctx))
+				break
 			}
-		}
-
-		env.TestRunRepo.UpdateTestRunStatus(runID, runStatus, &scenarioStatus, &sceanrioReasoning)
-		if _, err := env.ScenarioRepo.UpdateScenario(sID, map[string]interface{}{"status": scenarioStatus}); err != nil {
-			log.Printf("[SCENARIO-RUN][GOROUTINE][ERROR] Failed to update scenario status to %s for scenario_id=%d: %v", scenarioStatus, sID, err)
-		}
-
-		for _, h := range finalState.History {
-			interaction := repo.Interaction{
-				TestRunID:   runID,
-				ScenarioID:  sID,
-				TurnNumber:  int(h.Turn),
-				UserMessage: h.User,
-				LLMResponse: h.Assistant,
-			}
-			err := env.InteractionRepo.Create(&interaction)
+			execution.tag(proj, metadata)
+			runID, err := env.TestRunRepo.CreateTestRun(sID, metadata)
 			if err != nil {
-				log.Printf("[SCENARIO-RUN][GOROUTINE][ERROR] Failed to record interaction for scenario_id=%d, run_id=%d, turn=%d: %v", sID, runID, h.Turn, err)
+				log.Printf("[SCENARIO-RUN][GOROUTINE][ERROR] Failed to create test run entry for scenario_id=%d, data_row=%d, persona=%q: %v", sID, execution.Case.Row, personaName(execution.Persona), err)
+				status = combineStatus(status, "Fail")
+				continue
 			}
-		}
+			env.TestRunRepo.UpdateTestRunStatus(runID, "running", nil, nil)
+			env.Runs.AddRun(handle, sID, runID)
 
-		log.Printf("[SCENARIO-RUN][GOROUTINE] Run finished for scenario_id=%d. Final status: %s", sID, scenarioStatus)
-	}(scenarioID, testProject, scenario)
+			setup := scenarioSetup{Project: proj, Clients: clients, Prompts: prompts, Target: bot, JudgeName: models.judgeName(), Persona: execution.Persona, DataRow: execution.Case.Row}
+			outcome := env.executeScenarioRun(ctx, runID, sID, execution.Case.Scenario, setup)
+			status = combineStatus(status, outcome.Status)
+		}
+		if _, err := env.ScenarioRepo.UpdateScenario(sID, map[string]interface{}{"status": status}); err != nil {
+			log.Printf("[SCENARIO-RUN][GOROUTINE][ERROR] Failed to update scenario status to %s for scenario_id=%d: %v", status, sID, err)
+		}
+		log.Printf("[SCENARIO-RUN][GOROUTINE] Run finished for scenario_id=%d. Final status: %s", sID, status)
+	}(scenarioID, testProject)
 
 	// STEP 3: Immediately respond to the frontend
 	w.Header().Set("Content-Type", "application/json")
@@ -386,3 +555,116 @@ func (env *APIEnv) ScenarioRunHandler(w http.ResponseWriter, r *http.Request) {
 		"scenario_id": scenarioID,
 	})
 }
+
+// scenarioSetup is what a single-scenario run needs besides the scenario itself.
+type scenarioSetup struct {
+	Project   *repo.Test
+	Clients   *runClients
+	Prompts   runPrompts
+	Target    target.Target
+	JudgeName string
+	Persona   *repo.Persona // nil for the generic tester
+	DataRow   int           // Data table row the scenario was rendered with; 0 without a data table
+}
+
+// scenarioOutcome summarises a finished single-scenario run.
+type scenarioOutcome struct {
+	Status string // Scenario status: the verdict, "Fail" on errors and timeouts, or "Cancelled"
+	Turns  int
+}
+
+// interruptedStatus is the scenario status of the cases left over when ctx ends: "Fail" once
+// the run's timeout has passed, "Cancelled" after a stop request.
+func interruptedStatus(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "Fail"
+	}
+	return "Cancelled"
+}
+
+// interruptedRunStatus is the run status of the cases left over when ctx ends.
+func interruptedRunStatus(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "timed_out"
+	}
+	return "cancelled"
+}
+
+// executeScenarioRun runs the agent for one scenario under an existing runs row and stores
+// the run's status, verdict, scores, judgments and transcript.
+func (env *APIEnv) executeScenarioRun(ctx context.Context, runID, sID int, scen *repo.Scenario, setup scenarioSetup) scenarioOutcome {
+	proj := setup.Project
+	initialState := llm.CurrentState{
+		History:   []llm.HistoryItem{},
+		TurnCount: 0,
+		MaxTurns:  int16(proj.MaxInteractions),
+		Fulfilled: false,
+	}
+	testingAgent := agent.NewAgent(proj.Name, scen.Description, scen.ExpectedOutput, initialState, setup.Clients.Tester, env.DB)
+	setup.Clients.apply(testingAgent)
+	setup.Prompts.apply(testingAgent)
+	testingAgent.Target = setup.Target
+	testingAgent.StartEvent = proj.StartEvent
+	testingAgent.Persona = personaInput(setup.Persona)
+	testingAgent.Language = runLanguage(proj, scen, setup.Persona)
+	finalState, finalJudgement, agentErr := testingAgent.Run(ctx)
+
+	runStatus := "completed"
+	scenarioStatus := ""
+	sceanrioReasoning := ""
+	if finalJudgement != nil {
+		scenarioStatus = finalJudgement.Judgement
+		sceanrioReasoning = finalJudgement.EvidenceSummary
+	}
+	if errors.Is(agentErr, context.Canceled) {
+		log.Printf("[SCENARIO-RUN][GOROUTINE] Run cancelled for scenario_id=%d, run_id=%d", sID, runID)
+		runStatus = "cancelled"
+		scenarioStatus = "Cancelled"
+		sceanrioReasoning = "Run was stopped before completion"
+	} else if errors.Is(agentErr, context.DeadlineExceeded) {
+		log.Printf("[SCENARIO-RUN][GOROUTINE] Run timed out for scenario_id=%d, run_id=%d", sID, runID)
+		runStatus = "timed_out"
+		scenarioStatus = "Fail"
+		sceanrioReasoning = "Run timed out before completion: " + agentErr.Error()
+	} else if agentErr != nil || !finalState.Fulfilled {
+		runStatus = "failed"
+		if agentErr != nil {
+			scenarioStatus = "Fail"
+			sceanrioReasoning = agentErr.Error()
+		}
+	}
+
+	env.TestRunRepo.UpdateTestRunStatus(runID, runStatus, &scenarioStatus, &sceanrioReasoning)
+	if finalJudgement != nil {
+		if err := env.TestRunRepo.UpdateJudgmentScores(runID, finalJudgement.Confidence, finalJudgement.ScenarioCompletionScore, finalJudgement.ConversationQualityScore); err != nil {
+			log.Printf("[SCENARIO-RUN][GOROUTINE][ERROR] Failed to record judgment scores for run_id=%d: %v", runID, err)
+		}
+		if len(finalJudgement.Panel) > 0 {
+			if err := env.TestRunRepo.UpdateJudgeAgreement(runID, finalJudgement.AgreementRate); err != nil {
+				log.Printf("[SCENARIO-RUN][GOROUTINE][ERROR] Failed to record judge agreement for run_id=%d: %v", runID, err)
+			}
+		}
+		env.recordJudgments(runID, sID, setup.DataRow, personaName(setup.Persona), setup.JudgeName, finalJudgement)
+	}
+
+	outcome := scenarioOutcome{Status: scenarioStatus}
+	if finalState == nil {
+		log.Printf("[SCENARIO-RUN][GOROUTINE] Run finished for scenario_id=%d without a transcript. Final status: %s", sID, scenarioStatus)
+		return outcome
+	}
+	outcome.Turns = int(finalState.TurnCount)
+	if mismatches := languageMismatches(testingAgent.Language, finalState.History); mismatches > 0 {
+		log.Printf("[SCENARIO-RUN][GOROUTINE][WARN] %d VA replies not in %s for scenario_id=%d, run_id=%d", mismatches, testingAgent.Language, sID, runID)
+		if err := env.TestRunRepo.UpdateLanguageMismatches(runID, mismatches); err != nil {
+			log.Printf("[SCENARIO-RUN][GOROUTINE][ERROR] Failed to record language mismatches for run_id=%d: %v", runID, err)
+		}
+	}
+	for _, h := range finalState.History {
+		interaction := newInteraction(runID, sID, setup.DataRow, personaName(setup.Persona), h)
+		err := env.InteractionRepo.Create(&interaction)
+		if err != nil {
+			log.Printf("[SCENARIO-RUN][GOROUTINE][ERROR] Failed to record interaction for scenario_id=%d, run_id=%d, turn=%d: %v", sID, runID, h.Turn, err)
+		}
+	}
+	return outcome
+}
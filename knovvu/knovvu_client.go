@@ -2,7 +2,9 @@ package knovvu
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"evaluator/cassette"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,10 +12,71 @@ import (
 	"os"
 	"strings"
 	"time"
+)
 
-	"github.com/joho/godotenv"
+// Defaults used for any Config field left empty.
+const (
+	DefaultTenant       = "bac"
+	DefaultRegion       = "eu"
+	DefaultChannelID    = "ivr-default"
+	DefaultResponseType = "Text"
 )
 
+// Config describes how to reach the Knovvu VA of a project.
+type Config struct {
+	Tenant       string            // Sent as the Tenant header
+	Region       string            // Selects the regional endpoints, e.g. "eu"
+	BaseURL      string            // Overrides the regional VA endpoint, e.g. "https://eu.va.knovvu.com"
+	IdentityURL  string            // Overrides the regional token endpoint
+	ChannelID    string            // Channel the messages are sent on, e.g. "ivr-default"
+	ResponseType string            // Requested response type, e.g. "Text"
+	Headers      map[string]string // Extra headers sent with every message
+	ClientID     string            // Defaults to KNOVVU_CLIENT_ID
+	ClientSecret string            // Defaults to KNOVVU_CLIENT_SECRET
+}
+
+// Client talks to a single Knovvu VA.
+type Client struct {
+	cfg        Config
+	httpClient *http.Client
+	tokens     *TokenProvider
+}
+
+// NewClient creates a client, filling empty Config fields with the defaults.
+func NewClient(cfg Config) *Client {
+	if cfg.Tenant == "" {
+		cfg.Tenant = DefaultTenant
+	}
+	if cfg.Region == "" {
+		cfg.Region = DefaultRegion
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = fmt.Sprintf("https://%s.va.knovvu.com", cfg.Region)
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.IdentityURL == "" {
+		cfg.IdentityURL = fmt.Sprintf("https://identity.%s.va.knovvu.com/connect/token", cfg.Region)
+	}
+	if cfg.ChannelID == "" {
+		cfg.ChannelID = DefaultChannelID
+	}
+	if cfg.ResponseType == "" {
+		cfg.ResponseType = DefaultResponseType
+	}
+	if cfg.ClientID == "" {
+		cfg.ClientID = os.Getenv("KNOVVU_CLIENT_ID")
+	}
+	if cfg.ClientSecret == "" {
+		cfg.ClientSecret = os.Getenv("KNOVVU_CLIENT_SECRET")
+	}
+	return &Client{cfg: cfg, httpClient: &http.Client{Timeout: 15 * time.Second}, tokens: defaultTokens}
+}
+
+// Config returns the effective configuration of the client.
+func (c *Client) Config() Config {
+	return c.cfg
+}
+
 type KnovvuRequest struct {
 	Text         string            `json:"text"`
 	Conversation map[string]string `json:"conversation"`
@@ -21,6 +84,9 @@ type KnovvuRequest struct {
 	Type         string            `json:"type"`
 	Attachments  []any             `json:"attachments"`
 	ChannelData  map[string]any    `json:"channelData"`
+	Value        any               `json:"value,omitempty"` // Button payload of postBack and Action.Submit activities
+	Name         string            `json:"name,omitempty"`  // Name of an event activity
+	MembersAdded []map[string]any  `json:"membersAdded,omitempty"`
 }
 
 type KnovvuResponse struct {
@@ -34,131 +100,218 @@ type KnovvuResponse struct {
 	ChannelId    string                 `json:"channelId"`
 	ChannelData  map[string]interface{} `json:"channelData"`
 	Attachments  []interface{}          `json:"attachments"`
+
+	AttachmentLayout string      `json:"attachmentLayout,omitempty"`
+	SuggestedActions interface{} `json:"suggestedActions,omitempty"`
 }
 
-func GetKnovvuToken() (string, error) {
-	err := godotenv.Load(".env")
-	if err != nil {
-		return "", fmt.Errorf("error loading .env file: %w", err)
+// GetKnovvuToken returns a token for the default configuration.
+func GetKnovvuToken(ctx context.Context) (string, error) {
+	return NewClient(Config{}).GetToken(ctx)
+}
+
+// SendKnovvuMessage sends a message with the default configuration and the given token.
+// Only the first activity of the reply is returned.
+func SendKnovvuMessage(ctx context.Context, projectName, token, text, conversationID string) ([]byte, *KnovvuResponse, error) {
+	body, activities, _, err := NewClient(Config{}).send(ctx, projectName, token, conversationID, KnovvuRequest{Type: "message", Text: text})
+	if err != nil || len(activities) == 0 {
+		return body, nil, err
 	}
+	return body, &activities[0], nil
+}
 
-	clientID := os.Getenv("KNOVVU_CLIENT_ID")
-	clientSecret := os.Getenv("KNOVVU_CLIENT_SECRET")
-	if clientID == "" || clientSecret == "" {
+func (c *Client) tokenKey() tokenKey {
+	return tokenKey{identityURL: c.cfg.IdentityURL, clientID: c.cfg.ClientID, clientSecret: c.cfg.ClientSecret}
+}
+
+// GetToken returns an access token for the client's credentials. Tokens are cached until
+// shortly before they expire and shared by every client with the same credentials.
+func (c *Client) GetToken(ctx context.Context) (string, error) {
+	tape := cassette.FromContext(ctx)
+	if tape != nil && tape.Mode == cassette.ModeReplay {
+		// The recorded token response is served instead; no credentials are needed
+		token, _, err := c.fetchToken(ctx)
+		return token, err
+	}
+	if c.cfg.ClientID == "" || c.cfg.ClientSecret == "" {
 		return "", fmt.Errorf("client_id or client_secret not set in .env")
 	}
+	if tape != nil {
+		// Bypass the cache so the token request is on the cassette and replays stand alone
+		token, _, err := c.fetchToken(ctx)
+		return token, err
+	}
+	return c.tokens.Token(ctx, c.tokenKey(), c.fetchToken)
+}
+
+// fetchToken requests a new access token with the client credentials grant and returns it
+// together with its lifetime.
+func (c *Client) fetchToken(ctx context.Context) (string, time.Duration, error) {
 
 	form := url.Values{}
 	form.Add("grant_type", "client_credentials")
-	form.Add("client_id", clientID)
-	form.Add("client_secret", clientSecret)
+	form.Add("client_id", c.cfg.ClientID)
+	form.Add("client_secret", c.cfg.ClientSecret)
 
-	tokenURL := "https://identity.eu.va.knovvu.com/connect/token"
-	req, err := http.NewRequest("POST", tokenURL, bytes.NewBufferString(form.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.cfg.IdentityURL, bytes.NewBufferString(form.Encode()))
 	if err != nil {
-		return "", fmt.Errorf("failed to create token request: %w", err)
+		return "", 0, fmt.Errorf("failed to create token request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("token request failed: %w", err)
+		return "", 0, fmt.Errorf("token request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read token response: %w", err)
+		return "", 0, fmt.Errorf("failed to read token response: %w", err)
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", fmt.Errorf("token endpoint returned status %d: %s", resp.StatusCode, string(body))
+		return "", 0, fmt.Errorf("token endpoint returned status %d: %s", resp.StatusCode, string(body))
 	}
 
 	var tokenResp struct {
 		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
 	}
 	if err := json.Unmarshal(body, &tokenResp); err != nil {
-		return "", fmt.Errorf("failed to parse token response: %w", err)
+		return "", 0, fmt.Errorf("failed to parse token response: %w", err)
 	}
 
 	if tokenResp.AccessToken == "" {
-		return "", fmt.Errorf("access_token not found in response")
+		return "", 0, fmt.Errorf("access_token not found in response")
 	}
 
-	return tokenResp.AccessToken, nil
+	return tokenResp.AccessToken, time.Duration(tokenResp.ExpiresIn) * time.Second, nil
 }
 
-func SendKnovvuMessage(projectName, token, text, conversationID string) ([]byte, *KnovvuResponse, error) {
-	url := "https://eu.va.knovvu.com/magpie/ext-api/messages/synchronized"
+// SendMessage sends a user message to the VA and waits for its reply.
+func (c *Client) SendMessage(ctx context.Context, projectName, text, conversationID string) ([]byte, []KnovvuResponse, error) {
+	return c.SendActivity(ctx, projectName, conversationID, KnovvuRequest{Type: "message", Text: text})
+}
+
+// SendActivity sends an activity, such as a message or a button click, and waits for the
+// VA's reply. The conversation, channel and response type are filled in from the client.
+// If the VA rejects the cached token with a 401, the token is refreshed and the activity is
+// sent once more.
+func (c *Client) SendActivity(ctx context.Context, projectName, conversationID string, activity KnovvuRequest) ([]byte, []KnovvuResponse, error) {
+	token, err := c.GetToken(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get Knovvu token: %w", err)
+	}
+	body, resp, status, err := c.send(ctx, projectName, token, conversationID, activity)
+	if status != http.StatusUnauthorized {
+		return body, resp, err
+	}
+
+	c.tokens.Invalidate(c.tokenKey(), token)
+	if token, err = c.GetToken(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to refresh Knovvu token: %w", err)
+	}
+	body, resp, _, err = c.send(ctx, projectName, token, conversationID, activity)
+	return body, resp, err
+}
 
-	text = strings.TrimSpace(text)
+// send posts a single activity with token and returns the HTTP status alongside the reply.
+func (c *Client) send(ctx context.Context, projectName, token, conversationID string, requestBody KnovvuRequest) ([]byte, []KnovvuResponse, int, error) {
+	url := c.cfg.BaseURL + "/magpie/ext-api/messages/synchronized"
 
-	requestBody := KnovvuRequest{
-		Text: text,
-		Conversation: map[string]string{
-			"id": conversationID,
-		},
-		ChannelId:   "ivr-default",
-		Type:        "message",
-		Attachments: []interface{}{},
-		ChannelData: map[string]any{"responseType": "Text"},
+	requestBody.Text = strings.TrimSpace(requestBody.Text)
+	requestBody.Conversation = map[string]string{
+		"id": conversationID,
+	}
+	requestBody.ChannelId = c.cfg.ChannelID
+	if requestBody.Type == "" {
+		requestBody.Type = "message"
 	}
+	if requestBody.Attachments == nil {
+		requestBody.Attachments = []interface{}{}
+	}
+	channelData := map[string]any{"responseType": c.cfg.ResponseType}
+	for k, v := range requestBody.ChannelData {
+		channelData[k] = v
+	}
+	requestBody.ChannelData = channelData
 
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to marshal request body: %w", err)
+		return nil, nil, 0, fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, nil, 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Project", projectName)
 	req.Header.Set("X-Knovvu-Conversation-Id", conversationID)
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Tenant", "bac")
+	req.Header.Set("Tenant", c.cfg.Tenant)
+	for k, v := range c.cfg.Headers {
+		req.Header.Set(k, v)
+	}
 
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, nil, fmt.Errorf("request failed: %w", err)
+		return nil, nil, 0, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, nil, 0, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		fmt.Println("here reachedd")
 		// Attempt to parse error response for more details
 		var errorResponse map[string]any
 		if err := json.Unmarshal(body, &errorResponse); err == nil {
 			// If we can parse the JSON response, include it in the error message
 			errorJSON, _ := json.MarshalIndent(errorResponse, "", "  ")
-			return body, nil, fmt.Errorf("received non-2xx response: %d\nError details: %s",
+			return body, nil, resp.StatusCode, fmt.Errorf("received non-2xx response: %d\nError details: %s",
 				resp.StatusCode, string(errorJSON))
 		}
 
 		// If we can't parse the JSON, just return the raw body
-		return body, nil, fmt.Errorf("received non-2xx none_parse response: %d\nResponse body: %s",
+		return body, nil, resp.StatusCode, fmt.Errorf("received non-2xx none_parse response: %d\nResponse body: %s",
 			resp.StatusCode, string(body))
 	}
 
 	// Parse successful response
-	var knovvuResp KnovvuResponse
-	if err := json.Unmarshal(body, &knovvuResp); err != nil {
-		// Print the raw response body as a string
-		fmt.Println("Failed to parse response as JSON. Raw response body:")
-		fmt.Println(string(body))
-		return body, nil, fmt.Errorf("failed to parse successful response: %w\nResponse body: %s",
+	activities, err := parseActivities(body)
+	if err != nil {
+		return body, nil, resp.StatusCode, fmt.Errorf("failed to parse successful response: %w\nResponse body: %s",
 			err, string(body))
 	}
 
-	return body, &knovvuResp, nil
+	return body, activities, resp.StatusCode, nil
+}
+
+// parseActivities decodes a reply that is either a single activity, an array of activities
+// or an object with an "activities" array, and returns the activities in order.
+func parseActivities(body []byte) ([]KnovvuResponse, error) {
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var activities []KnovvuResponse
+		if err := json.Unmarshal(trimmed, &activities); err != nil {
+			return nil, err
+		}
+		return activities, nil
+	}
+	var single struct {
+		KnovvuResponse
+		Activities []KnovvuResponse `json:"activities"`
+	}
+	if err := json.Unmarshal(body, &single); err != nil {
+		return nil, err
+	}
+	if len(single.Activities) > 0 {
+		return single.Activities, nil
+	}
+	return []KnovvuResponse{single.KnovvuResponse}, nil
 }
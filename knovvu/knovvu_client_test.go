@@ -0,0 +1,92 @@
+package knovvu
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSendUsesProjectConfig(t *testing.T) {
+	ids := newIdentityServer(t, 3600)
+	var got *http.Request
+	var body KnovvuRequest
+	reply := `{"activities": [{"type": "message", "text": "one"}, {"type": "message", "text": "two"}]}`
+	va := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		json.NewDecoder(r.Body).Decode(&body)
+		io.WriteString(w, reply)
+	}))
+	defer va.Close()
+
+	c := NewClient(Config{
+		Tenant:       "acme",
+		BaseURL:      va.URL + "/",
+		IdentityURL:  ids.URL,
+		ChannelID:    "web",
+		ResponseType: "Card",
+		Headers:      map[string]string{"X-Env": "staging", "Tenant": "overridden"},
+		ClientID:     "id",
+		ClientSecret: "secret",
+	})
+	c.tokens = NewTokenProvider()
+	_, activities, err := c.SendActivity(context.Background(), "demo", "conv-1", KnovvuRequest{Text: "  hi  ", ChannelData: map[string]any{"locale": "tr-TR"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activities) != 2 || activities[1].Text != "two" {
+		t.Errorf("activities = %+v, want both", activities)
+	}
+	if got.URL.Path != "/magpie/ext-api/messages/synchronized" || got.Header.Get("Project") != "demo" || got.Header.Get("X-Knovvu-Conversation-Id") != "conv-1" {
+		t.Errorf("request = %s %v", got.URL.Path, got.Header)
+	}
+	// Extra headers are applied last, so a project can override the tenant header
+	if got.Header.Get("Tenant") != "overridden" || got.Header.Get("X-Env") != "staging" {
+		t.Errorf("headers = %v", got.Header)
+	}
+	if body.Text != "hi" || body.Type != "message" || body.ChannelId != "web" || body.Conversation["id"] != "conv-1" ||
+		body.ChannelData["responseType"] != "Card" || body.ChannelData["locale"] != "tr-TR" {
+		t.Errorf("body = %+v", body)
+	}
+
+	// Error details are returned, not printed
+	for _, tt := range []struct {
+		status     int
+		body, want string
+	}{
+		{http.StatusBadGateway, "upstream down", "upstream down"},
+		{http.StatusOK, "<html>maintenance</html>", "maintenance"},
+	} {
+		status := tt.status
+		va.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			io.WriteString(w, tt.body)
+		})
+		if _, _, err := c.SendMessage(context.Background(), "demo", "hi", "conv-1"); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("SendMessage() error = %v, want it to contain %q", err, tt.want)
+		}
+	}
+}
+
+func TestNewClientDefaults(t *testing.T) {
+	t.Setenv("KNOVVU_CLIENT_ID", "env-id")
+	t.Setenv("KNOVVU_CLIENT_SECRET", "env-secret")
+	cfg := NewClient(Config{Region: "us"}).Config()
+	want := Config{
+		Tenant:       DefaultTenant,
+		Region:       "us",
+		BaseURL:      "https://us.va.knovvu.com",
+		IdentityURL:  "https://identity.us.va.knovvu.com/connect/token",
+		ChannelID:    DefaultChannelID,
+		ResponseType: DefaultResponseType,
+		ClientID:     "env-id",
+		ClientSecret: "env-secret",
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("NewClient() config = %+v, want %+v", cfg, want)
+	}
+}
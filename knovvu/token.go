@@ -0,0 +1,100 @@
+package knovvu
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// tokenRefreshMargin is how long before expiry a cached token is replaced. Short-lived
+	// tokens are replaced after 3/4 of their lifetime instead, so they are still reused.
+	tokenRefreshMargin = 60 * time.Second
+	// defaultTokenLifetime is assumed when the identity endpoint omits expires_in.
+	defaultTokenLifetime = 5 * time.Minute
+)
+
+// tokenKey identifies the credentials a token was issued for.
+type tokenKey struct {
+	identityURL  string
+	clientID     string
+	clientSecret string
+}
+
+type cachedToken struct {
+	mu        sync.Mutex // held while the token is being fetched, so concurrent runs share one request
+	value     string
+	refreshAt time.Time
+	expiresAt time.Time
+}
+
+// TokenProvider caches access tokens per client credentials. It is safe for concurrent use.
+type TokenProvider struct {
+	mu     sync.Mutex
+	tokens map[tokenKey]*cachedToken
+	now    func() time.Time
+}
+
+// NewTokenProvider creates an empty token cache.
+func NewTokenProvider() *TokenProvider {
+	return &TokenProvider{tokens: make(map[tokenKey]*cachedToken), now: time.Now}
+}
+
+// defaultTokens is shared by every Client, as clients are created per run.
+var defaultTokens = NewTokenProvider()
+
+func (p *TokenProvider) entry(key tokenKey) *cachedToken {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t, ok := p.tokens[key]
+	if !ok {
+		t = &cachedToken{}
+		p.tokens[key] = t
+	}
+	return t
+}
+
+// Token returns a cached token for key, calling fetch when there is none or it is about to
+// expire. If a proactive refresh fails while the cached token is still valid, the cached
+// token is returned.
+func (p *TokenProvider) Token(ctx context.Context, key tokenKey, fetch func(ctx context.Context) (string, time.Duration, error)) (string, error) {
+	t := p.entry(key)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := p.now()
+	if t.value != "" && now.Before(t.refreshAt) {
+		return t.value, nil
+	}
+
+	value, lifetime, err := fetch(ctx)
+	if err != nil {
+		if t.value != "" && now.Before(t.expiresAt) {
+			return t.value, nil
+		}
+		return "", err
+	}
+	if lifetime <= 0 {
+		lifetime = defaultTokenLifetime
+	}
+	now = p.now()
+	t.value, t.refreshAt, t.expiresAt = value, now.Add(lifetime-refreshMargin(lifetime)), now.Add(lifetime)
+	return value, nil
+}
+
+// refreshMargin is how long before the end of lifetime a token is replaced: tokenRefreshMargin,
+// but at most a quarter of the lifetime.
+func refreshMargin(lifetime time.Duration) time.Duration {
+	return min(tokenRefreshMargin, lifetime/4)
+}
+
+// Invalidate drops token from the cache if it is still the cached token for key, so the
+// next call to Token fetches a new one.
+func (p *TokenProvider) Invalidate(key tokenKey, token string) {
+	t := p.entry(key)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.value == token {
+		t.value, t.refreshAt, t.expiresAt = "", time.Time{}, time.Time{}
+	}
+}
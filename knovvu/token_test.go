@@ -0,0 +1,228 @@
+package knovvu
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// identityServer issues "token-1", "token-2", ... with the given expires_in; 0 omits it.
+type identityServer struct {
+	*httptest.Server
+	issued    atomic.Int32
+	expiresIn int
+	fail      atomic.Bool
+	delay     time.Duration
+}
+
+func newIdentityServer(t *testing.T, expiresIn int) *identityServer {
+	s := &identityServer{expiresIn: expiresIn}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.fail.Load() {
+			http.Error(w, "identity down", http.StatusServiceUnavailable)
+			return
+		}
+		if r.FormValue("grant_type") != "client_credentials" || r.FormValue("client_secret") == "" {
+			http.Error(w, "bad grant", http.StatusBadRequest)
+			return
+		}
+		time.Sleep(s.delay)
+		n := s.issued.Add(1)
+		if s.expiresIn == 0 {
+			fmt.Fprintf(w, `{"access_token": "token-%d"}`, n)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": %d}`, n, s.expiresIn)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// clock is a settable time source for TokenProvider.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestClient(identityURL, baseURL string, tokens *TokenProvider) *Client {
+	c := NewClient(Config{IdentityURL: identityURL, BaseURL: baseURL, ClientID: "id", ClientSecret: "secret"})
+	c.tokens = tokens
+	return c
+}
+
+func TestTokenCache(t *testing.T) {
+	ids := newIdentityServer(t, 3600)
+	tokens := NewTokenProvider()
+	ctx := context.Background()
+
+	// Clients are created per run but share tokens of the same credentials
+	for i := 0; i < 3; i++ {
+		token, err := newTestClient(ids.URL, "", tokens).GetToken(ctx)
+		if err != nil || token != "token-1" {
+			t.Fatalf("GetToken() = %q, %v, want the cached token-1", token, err)
+		}
+	}
+	other := NewClient(Config{IdentityURL: ids.URL, ClientID: "id", ClientSecret: "other"})
+	other.tokens = tokens
+	if token, _ := other.GetToken(ctx); token != "token-2" {
+		t.Errorf("GetToken() with other credentials = %q, want its own token", token)
+	}
+	if n := ids.issued.Load(); n != 2 {
+		t.Errorf("identity server issued %d tokens, want 2", n)
+	}
+
+	c := newTestClient(ids.URL, "", tokens)
+	c.cfg.ClientSecret = ""
+	if _, err := c.GetToken(ctx); err == nil {
+		t.Errorf("GetToken() without a secret succeeded")
+	}
+}
+
+func TestTokenConcurrentFirstFetch(t *testing.T) {
+	ids := newIdentityServer(t, 3600)
+	ids.delay = 50 * time.Millisecond // keep the first fetch in flight while the others wait
+	tokens := NewTokenProvider()
+
+	var wg sync.WaitGroup
+	got := make([]string, 20)
+	errs := make([]error, 20)
+	for i := range got {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got[i], errs[i] = newTestClient(ids.URL, "", tokens).GetToken(context.Background())
+		}(i)
+	}
+	wg.Wait()
+	for i := range got {
+		if errs[i] != nil || got[i] != "token-1" {
+			t.Errorf("caller %d got %q, %v, want token-1", i, got[i], errs[i])
+		}
+	}
+	if n := ids.issued.Load(); n != 1 {
+		t.Errorf("identity server issued %d tokens to concurrent callers, want 1", n)
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	tests := []struct {
+		name       string
+		expiresIn  int
+		reusedAt   time.Duration // still served from the cache
+		refreshAt  time.Duration // replaced
+		validUntil time.Duration
+	}{
+		{"hour", 3600, 3539 * time.Second, 3541 * time.Second, time.Hour},
+		// Tokens shorter than the margin are kept for 3/4 of their lifetime, not refetched every call
+		{"short-lived", 40, 29 * time.Second, 31 * time.Second, 40 * time.Second},
+		{"one second", 1, 700 * time.Millisecond, 800 * time.Millisecond, time.Second},
+		{"no expires_in", 0, 4*time.Minute - time.Second, 4*time.Minute + 2*time.Second, defaultTokenLifetime},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := newIdentityServer(t, tt.expiresIn)
+			now := &clock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+			tokens := NewTokenProvider()
+			tokens.now = now.Now
+			c := newTestClient(ids.URL, "", tokens)
+			ctx := context.Background()
+
+			c.GetToken(ctx)
+			now.Advance(tt.reusedAt)
+			if token, _ := c.GetToken(ctx); token != "token-1" {
+				t.Errorf("GetToken() after %v = %q, want the cached token", tt.reusedAt, token)
+			}
+			now.Advance(tt.refreshAt - tt.reusedAt)
+			if token, _ := c.GetToken(ctx); token != "token-2" {
+				t.Errorf("GetToken() after %v = %q, want a new token", tt.refreshAt, token)
+			}
+
+			// A failed proactive refresh keeps the token until it really expires
+			ids.fail.Store(true)
+			now.Advance(tt.refreshAt)
+			if token, err := c.GetToken(ctx); err != nil || token != "token-2" {
+				t.Errorf("GetToken() with the identity server down = %q, %v, want the still valid token", token, err)
+			}
+			now.Advance(tt.validUntil)
+			if _, err := c.GetToken(ctx); err == nil {
+				t.Errorf("GetToken() returned an expired token")
+			}
+		})
+	}
+}
+
+func TestSendRetriesOnUnauthorized(t *testing.T) {
+	ids := newIdentityServer(t, 3600)
+	var mu sync.Mutex
+	var seen []string
+	rejected := map[string]bool{"Bearer token-1": true}
+	va := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		mu.Lock()
+		seen = append(seen, auth)
+		reject := rejected[auth] || rejected["*"]
+		mu.Unlock()
+		if reject {
+			http.Error(w, `{"error": "token revoked"}`, http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, `[{"type": "message", "text": "hello"}]`)
+	}))
+	defer va.Close()
+
+	tokens := NewTokenProvider()
+	c := newTestClient(ids.URL, va.URL, tokens)
+	ctx := context.Background()
+
+	// The revoked cached token is dropped and the message is sent once more with a new one
+	_, activities, err := c.SendMessage(ctx, "demo", "hi", "conv-1")
+	if err != nil || len(activities) != 1 || activities[0].Text != "hello" {
+		t.Fatalf("SendMessage() = %+v, %v", activities, err)
+	}
+	if want := []string{"Bearer token-1", "Bearer token-2"}; strings.Join(seen, ",") != strings.Join(want, ",") {
+		t.Errorf("VA saw %v, want %v", seen, want)
+	}
+	// Other clients pick up the new token instead of the revoked one
+	if token, _ := newTestClient(ids.URL, va.URL, tokens).GetToken(ctx); token != "token-2" {
+		t.Errorf("cached token = %q, want token-2", token)
+	}
+	// A stale invalidation doesn't drop the newer token
+	tokens.Invalidate(c.tokenKey(), "token-1")
+	if token, _ := c.GetToken(ctx); token != "token-2" {
+		t.Errorf("GetToken() after a stale Invalidate = %q, want token-2", token)
+	}
+
+	// A VA that keeps refusing is retried only once
+	mu.Lock()
+	rejected["*"], seen = true, nil
+	mu.Unlock()
+	_, _, err = c.SendMessage(ctx, "demo", "hi", "conv-1")
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "token revoked") {
+		t.Errorf("SendMessage() error = %v, want the VA's 401 and its details", err)
+	}
+	if len(seen) != 2 {
+		t.Errorf("VA saw %d attempts, want 2", len(seen))
+	}
+	if n := ids.issued.Load(); n != 3 {
+		t.Errorf("identity server issued %d tokens, want 3", n)
+	}
+}
@@ -27,6 +27,8 @@ type CohereResponseFormat struct {
 type CohereChatRequest struct {
 	Messages       []CohereChatMessage  `json:"messages"`
 	Temperature    float64              `json:"temperature"`
+	MaxTokens      int                  `json:"max_tokens,omitempty"`
+	Seed           *int                 `json:"seed,omitempty"`
 	Model          string               `json:"model"`
 	ResponseFormat CohereResponseFormat `json:"response_format"`
 }
@@ -48,8 +50,8 @@ type CohereChatResponse struct {
 }
 
 // GenerateContentREST implements LLM for CohereClient
-func (c *CohereClient) GenerateContentREST(prompt string, input LLMInput) (*LLMOutput, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), ContextTimeout)
+func (c *CohereClient) GenerateContentREST(ctx context.Context, prompt string, input LLMInput, opts CallOptions) (*LLMOutput, error) {
+	ctx, cancel := callContext(ctx)
 	defer cancel()
 
 	apiKey := c.apiKey
@@ -114,14 +116,25 @@ func (c *CohereClient) GenerateContentREST(prompt string, input LLMInput) (*LLMO
 				"type":        "string",
 				"description": "Notes on how you're adapting based on observed VA patterns",
 			},
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{ActionMessage, ActionSelectOption},
+				"description": "message to type next_message, select_option to click the option named by selected_option",
+			},
+			"selected_option": map[string]interface{}{
+				"type":        "string",
+				"description": "Title or value of the option to click; empty unless action is select_option",
+			},
 		},
-		"required": []string{"next_message", "reasoning", "fulfilled", "confidence", "strategy", "safety_check", "error_logs", "adaptation_notes"},
+		"required": []string{"next_message", "reasoning", "fulfilled", "confidence", "strategy", "safety_check", "error_logs", "adaptation_notes", "action", "selected_option"},
 	}
 
 	requestBody := CohereChatRequest{
 		Messages:       messages,
-		Temperature:    0,
-		Model:          c.Model,
+		Temperature:    opts.Temperature,
+		MaxTokens:      opts.maxTokens(0),
+		Seed:           opts.Seed,
+		Model:          opts.model(c.Model),
 		ResponseFormat: CohereResponseFormat{Type: "json_object", JSONSchema: jsonSchema},
 	}
 
@@ -194,8 +207,8 @@ func (c *CohereClient) GenerateContentREST(prompt string, input LLMInput) (*LLMO
 	return &output, nil
 }
 
-func (c *CohereClient) GenerateJudgmentREST(judgePrompt string, input JudgeInput) (*JudgmentResult, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), ContextTimeout)
+func (c *CohereClient) GenerateJudgmentREST(ctx context.Context, judgePrompt string, input JudgeInput, opts CallOptions) (*JudgmentResult, error) {
+	ctx, cancel := callContext(ctx)
 	defer cancel()
 
 	apiKey := c.apiKey
@@ -251,8 +264,10 @@ func (c *CohereClient) GenerateJudgmentREST(judgePrompt string, input JudgeInput
 
 	requestBody := CohereChatRequest{
 		Messages:       messages,
-		Temperature:    0,
-		Model:          c.Model,
+		Temperature:    opts.Temperature,
+		MaxTokens:      opts.maxTokens(0),
+		Seed:           opts.Seed,
+		Model:          opts.model(c.Model),
 		ResponseFormat: CohereResponseFormat{Type: "json_object", JSONSchema: jsonSchema},
 	}
 
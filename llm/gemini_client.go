@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"time"
 )
 
@@ -32,17 +31,22 @@ type Part struct {
 
 // GenerationConfig represents the generation configuration for the model.
 type GenerationConfig struct {
-	Temperature      float64 `json:"temperature,omitempty"`
+	Temperature      float64 `json:"temperature"`
+	MaxOutputTokens  int     `json:"max_output_tokens,omitempty"`
+	Seed             *int    `json:"seed,omitempty"`
 	ResponseMIMEType string  `json:"response_mime_type,omitempty"`
 	ResponseSchema   *Schema `json:"response_schema,omitempty"`
-	// Other fields like TopK, TopP, MaxOutputTokens can be added here
+	// Other fields like TopK, TopP can be added here
 }
 
 // Schema defines the expected structure of the JSON output.
 type Schema struct {
-	Type       string             `json:"type"`
-	Properties map[string]*Schema `json:"properties,omitempty"`
-	Items      *Schema            `json:"items,omitempty"` // For array types
+	Type        string             `json:"type"`
+	Description string             `json:"description,omitempty"`
+	Enum        []string           `json:"enum,omitempty"` // For STRING types with a fixed set of values
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"` // For array types
 }
 
 // GeminiAPIResponse represents the structure of the response from the Gemini generateContent API.
@@ -56,19 +60,11 @@ type GeminiAPIResponse struct {
 
 // GenerateContentREST interacts with the Gemini LLM via REST API to generate content based on the input.
 // It takes an LLMInput struct and returns an LLMOutput struct or an error.
-func (c *GeminiClient) GenerateContentREST(prompt string, input LLMInput) (*LLMOutput, error) {
+func (c *GeminiClient) GenerateContentREST(ctx context.Context, prompt string, input LLMInput, opts CallOptions) (*LLMOutput, error) {
 	// Set up a context with a timeout
-	ctx, cancel := context.WithTimeout(context.Background(), ContextTimeout)
+	ctx, cancel := callContext(ctx)
 	defer cancel()
 
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("GEMINI_API_KEY environment variable not set")
-	}
-
-	modelName := "gemini-2.5-flash"
-	apiEndpoint := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", modelName, apiKey)
-
 	// Convert the LLMInput struct to a JSON string for the user prompt
 	inputJSONBytes, err := json.Marshal(input)
 	if err != nil {
@@ -96,7 +92,9 @@ func (c *GeminiClient) GenerateContentREST(prompt string, input LLMInput) (*LLMO
 			},
 		},
 		GenerationConfig: &GenerationConfig{
-			Temperature:      0,
+			Temperature:      opts.Temperature,
+			MaxOutputTokens:  opts.maxTokens(0),
+			Seed:             opts.Seed,
 			ResponseMIMEType: "application/json",
 			ResponseSchema: &Schema{
 				Type: "OBJECT",
@@ -112,19 +110,116 @@ func (c *GeminiClient) GenerateContentREST(prompt string, input LLMInput) (*LLMO
 						Items: &Schema{Type: "STRING"},
 					},
 					"adaptation_notes": {Type: "STRING"},
+					"action":           {Type: "STRING", Enum: []string{ActionMessage, ActionSelectOption}},
+					"selected_option":  {Type: "STRING"},
 				},
 			},
 		},
 	}
 
+	rawJSONOutput, err := c.generateContent(ctx, opts.model(c.Model), requestBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var output LLMOutput
+	err = json.Unmarshal([]byte(rawJSONOutput), &output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal final LLM output schema: %w", err)
+	}
+
+	return &output, nil
+}
+
+// GenerateJudgmentREST asks Gemini for a final verdict using a response schema,
+// so the response is guaranteed to match JudgmentResult.
+func (c *GeminiClient) GenerateJudgmentREST(ctx context.Context, judgePrompt string, input JudgeInput, opts CallOptions) (*JudgmentResult, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+
+	inputJSONBytes, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JudgeInput: %w", err)
+	}
+
+	requestBody := GeminiAPIRequest{
+		Contents: []Content{
+			{
+				Role:  "user",
+				Parts: []Part{{Text: string(inputJSONBytes)}},
+			},
+		},
+		SystemInstruction: &Content{
+			Role:  "system",
+			Parts: []Part{{Text: judgePrompt}},
+		},
+		GenerationConfig: &GenerationConfig{
+			Temperature:      opts.Temperature,
+			MaxOutputTokens:  opts.maxTokens(0),
+			Seed:             opts.Seed,
+			ResponseMIMEType: "application/json",
+			ResponseSchema: &Schema{
+				Type: "OBJECT",
+				Properties: map[string]*Schema{
+					"judgment": {
+						Type:        "STRING",
+						Enum:        []string{JudgementPass, JudgementFail, JudgementHumanReview},
+						Description: "Final verdict on whether the scenario was completed as intended",
+					},
+					"confidence": {
+						Type:        "STRING",
+						Enum:        []string{"high", "medium", "low"},
+						Description: "Level of confidence in the judgment",
+					},
+					"evidence_summary": {
+						Type:        "STRING",
+						Description: "Concise summary of evidence from the conversation that led to the verdict",
+					},
+					"scenario_completion_score": {
+						Type:        "NUMBER",
+						Description: "Score 0-1 for scenario completion",
+					},
+					"conversation_quality_score": {
+						Type:        "NUMBER",
+						Description: "Score 0-1 for overall conversation quality",
+					},
+				},
+				Required: []string{"judgment", "confidence", "evidence_summary", "scenario_completion_score", "conversation_quality_score"},
+			},
+		},
+	}
+
+	rawJSONOutput, err := c.generateContent(ctx, opts.model(c.Model), requestBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var result JudgmentResult
+	if err := json.Unmarshal([]byte(rawJSONOutput), &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JudgmentResult from Gemini response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// generateContent posts requestBody to the generateContent endpoint of the given model with
+// retries and returns the text of the first candidate.
+func (c *GeminiClient) generateContent(ctx context.Context, modelName string, requestBody GeminiAPIRequest) (string, error) {
+	apiKey := c.apiKey
+	if apiKey == "" {
+		return "", fmt.Errorf("GEMINI_API_KEY environment variable not set")
+	}
+
+	apiEndpoint := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", modelName, apiKey)
+
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal API request body: %w", err)
+		return "", fmt.Errorf("failed to marshal API request body: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", apiEndpoint, bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return "", fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -146,7 +241,7 @@ func (c *GeminiClient) GenerateContentREST(prompt string, input LLMInput) (*LLMO
 		// Check if context is done before making the request
 		select {
 		case <-ctx.Done():
-			return nil, fmt.Errorf("operation canceled or timed out: %w", ctx.Err())
+			return "", fmt.Errorf("operation canceled or timed out: %w", ctx.Err())
 		default:
 			// Continue with the request
 		}
@@ -163,7 +258,7 @@ func (c *GeminiClient) GenerateContentREST(prompt string, input LLMInput) (*LLMO
 		// Create a fresh request for each attempt
 		reqCopy, err := http.NewRequestWithContext(ctx, "POST", apiEndpoint, bytes.NewBuffer(jsonBody))
 		if err != nil {
-			return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+			return "", fmt.Errorf("failed to create HTTP request: %w", err)
 		}
 		reqCopy.Header.Set("Content-Type", "application/json")
 
@@ -222,40 +317,40 @@ func (c *GeminiClient) GenerateContentREST(prompt string, input LLMInput) (*LLMO
 		// Check if the overall context timed out
 		select {
 		case <-ctx.Done():
-			return nil, fmt.Errorf("operation canceled or timed out after %d attempts: %w. Last error: %v", maxRetries, ctx.Err(), lastErr)
+			return "", fmt.Errorf("operation canceled or timed out after %d attempts: %w. Last error: %v", maxRetries, ctx.Err(), lastErr)
 		default:
-			return nil, fmt.Errorf("all %d request attempts failed due to network/client errors, last error: %w", maxRetries, lastErr)
+			return "", fmt.Errorf("all %d request attempts failed due to network/client errors, last error: %w", maxRetries, lastErr)
 		}
 	}
 	if resp == nil { // Should not happen if lastErr is nil, but as a safeguard
-		return nil, fmt.Errorf("no response received from Gemini API after %d attempts", maxRetries)
+		return "", fmt.Errorf("no response received from Gemini API after %d attempts", maxRetries)
 	}
 	defer resp.Body.Close() // Ensure body is closed here for the successful or final failed response
 
 	if resp.StatusCode != http.StatusOK {
 		responseBodyBytes, readErr := io.ReadAll(resp.Body)
 		if readErr != nil {
-			return nil, fmt.Errorf("API returned non-OK status: %d, and failed to read error body: %w", resp.StatusCode, readErr)
+			return "", fmt.Errorf("API returned non-OK status: %d, and failed to read error body: %w", resp.StatusCode, readErr)
 		}
-		return nil, fmt.Errorf("API returned non-OK status: %d, body: %s", resp.StatusCode, string(responseBodyBytes))
+		return "", fmt.Errorf("API returned non-OK status: %d, body: %s", resp.StatusCode, string(responseBodyBytes))
 	}
 
 	responseBodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return "", fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	var geminiResponse GeminiAPIResponse
 	err = json.Unmarshal(responseBodyBytes, &geminiResponse)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal Gemini API response: %w", err)
+		return "", fmt.Errorf("failed to unmarshal Gemini API response: %w", err)
 	}
 
 	if len(geminiResponse.Candidates) == 0 {
-		return nil, fmt.Errorf("no candidates returned from LLM API")
+		return "", fmt.Errorf("no candidates returned from LLM API")
 	}
 	if len(geminiResponse.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no content parts in the first candidate from LLM API")
+		return "", fmt.Errorf("no content parts in the first candidate from LLM API")
 	}
 
 	rawJSONOutput := geminiResponse.Candidates[0].Content.Parts[0].Text
@@ -267,14 +362,8 @@ func (c *GeminiClient) GenerateContentREST(prompt string, input LLMInput) (*LLMO
 	// For now, let's assume an empty text string from the Part is an issue.
 	if rawJSONOutput == "" {
 		// Consider if this should be a more structured error or a specific LLMOutput state
-		return nil, fmt.Errorf("LLM API returned empty text content in the response part")
+		return "", fmt.Errorf("LLM API returned empty text content in the response part")
 	}
 
-	var output LLMOutput
-	err = json.Unmarshal([]byte(rawJSONOutput), &output)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal final LLM output schema: %w", err)
-	}
-
-	return &output, nil
+	return rawJSONOutput, nil
 }
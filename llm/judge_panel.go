@@ -0,0 +1,161 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// PanelJudge is one member of a judge panel. The same LLM may appear several times
+// (with a non-zero temperature) to sample it repeatedly.
+type PanelJudge struct {
+	Name    string // Recorded with the judge's output, e.g. "openai/gpt-4.1#2"
+	LLM     LLM
+	Options CallOptions
+}
+
+// PanelJudgment is the output of a single panel member. Result is nil when the judge failed.
+type PanelJudgment struct {
+	Judge  string          `json:"judge"`
+	Result *JudgmentResult `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// JudgePanel asks several judges for a verdict on the same conversation and combines them.
+type JudgePanel struct {
+	Judges []PanelJudge
+	// MinAgreement is the share of the panel that must agree on the verdict for it to stand
+	// (0-1); failed judges count against it. Below it, or when the top verdicts tie, the
+	// consensus is escalated to Human_review. 0 means unanimity.
+	MinAgreement float64
+}
+
+// Judge runs every panel member concurrently and returns the consensus. The returned result
+// carries the agreement rate and the individual judgments. It fails only if no judge succeeds.
+func (p *JudgePanel) Judge(ctx context.Context, judgePrompt string, input JudgeInput) (*JudgmentResult, error) {
+	if len(p.Judges) == 0 {
+		return nil, fmt.Errorf("judge panel has no judges")
+	}
+
+	judgments := make([]PanelJudgment, len(p.Judges))
+	var wg sync.WaitGroup
+	for i, j := range p.Judges {
+		wg.Add(1)
+		go func(i int, j PanelJudge) {
+			defer wg.Done()
+			judgments[i].Judge = j.Name
+			result, err := j.LLM.GenerateJudgmentREST(ctx, judgePrompt, input, j.Options)
+			if err != nil {
+				judgments[i].Error = err.Error()
+				return
+			}
+			judgments[i].Result = result
+		}(i, j)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return p.consensus(judgments)
+}
+
+// consensus combines the successful judgments into a single result. The agreement rate is
+// taken over the whole panel, including the judges that failed.
+func (p *JudgePanel) consensus(judgments []PanelJudgment) (*JudgmentResult, error) {
+	votes := make(map[string]int)
+	var order []string // first-seen order keeps ties deterministic
+	var completion, quality float64
+	succeeded := 0
+	for _, j := range judgments {
+		if j.Result == nil {
+			continue
+		}
+		succeeded++
+		verdict := normalizeVerdict(j.Result.Judgement)
+		if votes[verdict] == 0 {
+			order = append(order, verdict)
+		}
+		votes[verdict]++
+		completion += j.Result.ScenarioCompletionScore
+		quality += j.Result.ConversationQualityScore
+	}
+	if succeeded == 0 {
+		var errs []string
+		for _, j := range judgments {
+			errs = append(errs, j.Judge+": "+j.Error)
+		}
+		return nil, fmt.Errorf("all judges failed: %s", strings.Join(errs, "; "))
+	}
+
+	majority := order[0]
+	tie := false
+	for _, v := range order[1:] {
+		switch {
+		case votes[v] > votes[majority]:
+			majority, tie = v, false
+		case votes[v] == votes[majority]:
+			tie = true
+		}
+	}
+	// Failed judges count as dissent, so a verdict can't stand on a minority of the panel.
+	agreement := float64(votes[majority]) / float64(len(judgments))
+
+	minAgreement := p.MinAgreement
+	if minAgreement <= 0 {
+		minAgreement = 1
+	}
+
+	result := &JudgmentResult{
+		Judgement:                majority,
+		Confidence:               "high",
+		ScenarioCompletionScore:  completion / float64(succeeded),
+		ConversationQualityScore: quality / float64(succeeded),
+		AgreementRate:            agreement,
+		Panel:                    judgments,
+	}
+	switch {
+	case tie, agreement < minAgreement:
+		result.Judgement = JudgementHumanReview
+		result.Confidence = "low"
+	case agreement < 1:
+		result.Confidence = "medium"
+	}
+	result.EvidenceSummary = panelSummary(result, judgments, votes[majority])
+	return result, nil
+}
+
+// panelSummary describes how the panel voted, followed by the evidence of the judges that
+// agree with the consensus (or of every judge when the verdict was escalated).
+func panelSummary(result *JudgmentResult, judgments []PanelJudgment, majorityVotes int) string {
+	var sb strings.Builder
+	if result.Judgement == JudgementHumanReview && majorityVotes < len(judgments) {
+		fmt.Fprintf(&sb, "Judges disagreed (%d/%d agreement), escalated to human review.", majorityVotes, len(judgments))
+	} else {
+		fmt.Fprintf(&sb, "%d/%d judges agreed on %s.", majorityVotes, len(judgments), result.Judgement)
+	}
+	for _, j := range judgments {
+		if j.Result == nil {
+			fmt.Fprintf(&sb, "\n[%s] failed: %s", j.Judge, j.Error)
+			continue
+		}
+		if result.Judgement == JudgementHumanReview || normalizeVerdict(j.Result.Judgement) == result.Judgement {
+			fmt.Fprintf(&sb, "\n[%s] %s: %s", j.Judge, j.Result.Judgement, j.Result.EvidenceSummary)
+		}
+	}
+	return sb.String()
+}
+
+// normalizeVerdict maps the free-text verdicts some providers return onto the judge prompt's values.
+func normalizeVerdict(v string) string {
+	switch strings.ReplaceAll(strings.ToLower(strings.TrimSpace(v)), " ", "_") {
+	case "pass", "passed":
+		return JudgementPass
+	case "fail", "failed":
+		return JudgementFail
+	case "human_review", "human_review_required":
+		return JudgementHumanReview
+	}
+	return v
+}
@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeVerdict(t *testing.T) {
+	for in, want := range map[string]string{
+		"Pass":                  JudgementPass,
+		"Pass ":                 JudgementPass,
+		"  passed\n":            JudgementPass,
+		"FAIL":                  JudgementFail,
+		"Failed":                JudgementFail,
+		"Human review":          JudgementHumanReview,
+		" human_review ":        JudgementHumanReview,
+		"Human Review Required": JudgementHumanReview,
+		"Unsure":                "Unsure", // unknown verdicts are kept for the panel summary
+	} {
+		if got := normalizeVerdict(in); got != want {
+			t.Errorf("normalizeVerdict(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// stubJudge returns a fixed verdict, or fails when verdict is empty.
+type stubJudge struct {
+	verdict string
+	score   float64
+}
+
+func (s stubJudge) GenerateContentREST(ctx context.Context, prompt string, input LLMInput, opts CallOptions) (*LLMOutput, error) {
+	return nil, errors.New("not a simulator")
+}
+
+func (s stubJudge) GenerateJudgmentREST(ctx context.Context, judgePrompt string, input JudgeInput, opts CallOptions) (*JudgmentResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if s.verdict == "" {
+		return nil, errors.New("quota exceeded")
+	}
+	return &JudgmentResult{Judgement: s.verdict, EvidenceSummary: "saw " + s.verdict, ScenarioCompletionScore: s.score, ConversationQualityScore: s.score}, nil
+}
+
+func panelOf(minAgreement float64, judges ...stubJudge) *JudgePanel {
+	p := &JudgePanel{MinAgreement: minAgreement}
+	for i, j := range judges {
+		p.Judges = append(p.Judges, PanelJudge{Name: fmt.Sprintf("judge#%d", i+1), LLM: j})
+	}
+	return p
+}
+
+func TestJudgePanelConsensus(t *testing.T) {
+	pass, fail, review, broken := stubJudge{JudgementPass, 1}, stubJudge{JudgementFail, 0}, stubJudge{"Human review", 0.5}, stubJudge{}
+
+	tests := []struct {
+		name           string
+		panel          *JudgePanel
+		wantVerdict    string
+		wantConfidence string
+		wantAgreement  float64
+	}{
+		{"unanimous", panelOf(0, pass, stubJudge{"passed ", 1}, pass), JudgementPass, "high", 1},
+		{"majority that meets the minimum", panelOf(0.6, fail, fail, pass), JudgementFail, "medium", 2.0 / 3},
+		{"any disagreement escalates by default", panelOf(0, fail, fail, pass), JudgementHumanReview, "low", 2.0 / 3},
+		// A 50% minimum would let either side of a 1-1 split stand; ties always escalate
+		{"even split", panelOf(0.5, pass, fail), JudgementHumanReview, "low", 0.5},
+		{"tie of the leading verdicts behind a third one", panelOf(0.4, pass, fail, fail, pass, review), JudgementHumanReview, "low", 0.4},
+		// One judge answering is not a consensus of three
+		{"failed judges count against the agreement", panelOf(0.6, pass, broken, broken), JudgementHumanReview, "low", 1.0 / 3},
+		{"majority despite a failed judge", panelOf(0.6, pass, pass, broken), JudgementPass, "medium", 2.0 / 3},
+		{"escalation agreed on by the panel", panelOf(0, review, review), JudgementHumanReview, "high", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.panel.Judge(context.Background(), "judge", JudgeInput{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Judgement != tt.wantVerdict || got.Confidence != tt.wantConfidence || math.Abs(got.AgreementRate-tt.wantAgreement) > 1e-9 {
+				t.Errorf("Judge() = %s, %s confidence, %.2f agreement; want %s, %s, %.2f",
+					got.Judgement, got.Confidence, got.AgreementRate, tt.wantVerdict, tt.wantConfidence, tt.wantAgreement)
+			}
+			if len(got.Panel) != len(tt.panel.Judges) {
+				t.Errorf("Panel has %d judgments, want one per judge", len(got.Panel))
+			}
+		})
+	}
+}
+
+func TestJudgePanelScoresAndSummary(t *testing.T) {
+	p := panelOf(0.5, stubJudge{JudgementPass, 0.9}, stubJudge{JudgementPass, 0.5}, stubJudge{JudgementFail, 0.1}, stubJudge{})
+	got, err := p.Judge(context.Background(), "judge", JudgeInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Scores are averaged over the judges that answered
+	if math.Abs(got.ScenarioCompletionScore-0.5) > 1e-9 {
+		t.Errorf("ScenarioCompletionScore = %v, want 0.5", got.ScenarioCompletionScore)
+	}
+	if got.Panel[3].Result != nil || got.Panel[3].Error != "quota exceeded" {
+		t.Errorf("failed judge recorded as %+v", got.Panel[3])
+	}
+	for _, want := range []string{"2/4 judges agreed on Pass.", "[judge#1] Pass: saw Pass", "[judge#4] failed: quota exceeded"} {
+		if !strings.Contains(got.EvidenceSummary, want) {
+			t.Errorf("EvidenceSummary = %q, missing %q", got.EvidenceSummary, want)
+		}
+	}
+	// Only the evidence of the judges that agree with the consensus is kept
+	if strings.Contains(got.EvidenceSummary, "saw Fail") {
+		t.Errorf("EvidenceSummary = %q, includes the dissenting judge", got.EvidenceSummary)
+	}
+}
+
+func TestJudgePanelFailures(t *testing.T) {
+	if _, err := (&JudgePanel{}).Judge(context.Background(), "judge", JudgeInput{}); err == nil {
+		t.Error("an empty panel judged")
+	}
+	_, err := panelOf(0, stubJudge{}, stubJudge{}).Judge(context.Background(), "judge", JudgeInput{})
+	if err == nil || !strings.Contains(err.Error(), "judge#1: quota exceeded; judge#2: quota exceeded") {
+		t.Errorf("Judge() error = %v, want every judge's error", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := panelOf(0, stubJudge{JudgementPass, 1}).Judge(ctx, "judge", JudgeInput{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Judge() error = %v, want context.Canceled", err)
+	}
+}
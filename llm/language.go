@@ -0,0 +1,144 @@
+package llm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Languages maps the supported conversation languages, as ISO 639-1 codes, to their names.
+var Languages = map[string]string{
+	"ar": "Arabic",
+	"de": "German",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"tr": "Turkish",
+}
+
+// NormalizeLanguage accepts a language code or English name, e.g. "tr" or "Turkish", and
+// returns its code. An empty value stays empty.
+func NormalizeLanguage(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return "", nil
+	}
+	for code, name := range Languages {
+		if value == code || value == strings.ToLower(name) {
+			return code, nil
+		}
+	}
+	codes := make([]string, 0, len(Languages))
+	for code := range Languages {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return "", fmt.Errorf("unsupported language %q, expected one of %s", value, strings.Join(codes, ", "))
+}
+
+// Language is the language a run is conducted in, sent to the simulator and the judge with
+// Guidelines for their role, so it also works with prompts that don't describe it.
+type Language struct {
+	Code       string   `json:"code"`
+	Name       string   `json:"name"`
+	Guidelines []string `json:"guidelines"`
+}
+
+// SimulatorLanguage directs the simulator to converse in the language with the given code.
+// It returns nil for an empty code.
+func SimulatorLanguage(code string) *Language {
+	if code == "" {
+		return nil
+	}
+	name := Languages[code]
+	return &Language{Code: code, Name: name, Guidelines: []string{
+		fmt.Sprintf("Write every next_message in %s, as a native speaker would, even if the VA answers in another language.", name),
+		"Don't ask the VA to switch languages unless the scenario says so.",
+		"Keep reasoning, strategy and the other output fields in English.",
+	}}
+}
+
+// JudgeLanguage tells the judge which language the conversation was meant to be in. It
+// returns nil for an empty code.
+func JudgeLanguage(code string) *Language {
+	if code == "" {
+		return nil
+	}
+	name := Languages[code]
+	return &Language{Code: code, Name: name, Guidelines: []string{
+		fmt.Sprintf("The user wrote in %s; judge whether the VA understood and answered appropriately in %s.", name, name),
+		"va_language in each turn is the language detected in the VA's reply, empty when it could not be detected.",
+		fmt.Sprintf("A VA reply in a language other than %s is a defect: lower conversation_quality_score and mention it in evidence_summary.", name),
+		"Write evidence_summary in English.",
+	}}
+}
+
+// Frequent words of each Latin-script language, used by DetectLanguage.
+var languageStopwords = map[string][]string{
+	"de": {"und", "der", "die", "das", "ist", "nicht", "sie", "ich", "mit", "für", "bitte", "ihre", "ein", "eine", "zu", "auf", "wie", "kann", "haben", "danke"},
+	"en": {"the", "and", "you", "your", "is", "are", "to", "of", "for", "please", "can", "have", "with", "this", "what", "how", "thank", "help", "will", "would"},
+	"es": {"el", "la", "los", "las", "que", "es", "para", "por", "con", "su", "una", "gracias", "usted", "puede", "cómo", "qué", "está", "tiene", "del", "favor"},
+	"fr": {"le", "la", "les", "et", "est", "vous", "votre", "pour", "une", "des", "que", "pas", "avec", "merci", "pouvez", "comment", "je", "nous", "sur", "du"},
+	"tr": {"ve", "bir", "bu", "için", "ile", "mi", "mı", "mu", "mü", "ne", "nasıl", "lütfen", "teşekkür", "size", "sizin", "var", "yok", "değil", "olarak", "yardımcı"},
+}
+
+// Letters that only one of the Latin-script languages uses.
+var languageLetters = map[string]string{
+	"de": "äß",
+	"es": "ñ¿¡",
+	"tr": "ğış",
+}
+
+// DetectLanguage guesses the language of a VA reply among Languages. It returns the code, or
+// "" when the text is too short or ambiguous to tell.
+func DetectLanguage(text string) string {
+	var arabic, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+	if arabic > latin {
+		return "ar"
+	}
+	if latin < 8 {
+		return ""
+	}
+
+	scores := make(map[string]int)
+	lower := strings.ToLower(text)
+	words := strings.FieldsFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) })
+	for _, word := range words {
+		for code, stopwords := range languageStopwords {
+			for _, s := range stopwords {
+				if word == s {
+					scores[code]++
+				}
+			}
+		}
+	}
+	for code, letters := range languageLetters {
+		for _, r := range lower {
+			if strings.ContainsRune(letters, r) {
+				scores[code]++
+			}
+		}
+	}
+
+	best, bestScore, runnerUp := "", 0, 0
+	for code, score := range scores {
+		if score > bestScore {
+			best, bestScore, runnerUp = code, score, bestScore
+		} else if score > runnerUp {
+			runnerUp = score
+		}
+	}
+	if bestScore < 2 || bestScore == runnerUp {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,64 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"english", "Thank you for your message. How can I help you with your account?", "en"},
+		{"turkish", "Merhaba, size nasıl yardımcı olabilirim? Lütfen hesap numaranızı yazın.", "tr"},
+		{"german", "Bitte geben Sie Ihre Kundennummer ein, damit ich Ihnen helfen kann.", "de"},
+		{"spanish", "Gracias por su mensaje. ¿Cómo puedo ayudarle con su cuenta?", "es"},
+		{"french", "Merci pour votre message. Comment pouvez-vous nous aider avec votre compte ?", "fr"},
+		{"upper case", "THANK YOU, HOW CAN I HELP YOU TODAY?", "en"},
+		// Letters only Turkish uses outweigh a borrowed English word
+		{"turkish letters without stopwords", "Şifrenizi değiştirmek için Ayarlar menüsünü açın, the link", "tr"},
+		{"arabic with latin names", "مرحبا بك في Demo Bank، كيف يمكنني مساعدتك اليوم؟", "ar"},
+		{"latin majority over arabic", "Your IBAN is TR00 0001 and the reference is مرجع", "en"},
+		// Too little evidence is better reported as unknown than as a mismatch
+		{"too few letters", "OK 123", ""},
+		{"amounts and codes", "Balance: 1.250,00 TRY (REF-88X2)", ""},
+		{"a single frequent word", "Account overview of March", ""},
+		{"shared words", "la la la la la la la", ""}, // Spanish and French both use "la"
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectLanguage(tt.text); got != tt.want {
+				t.Errorf("DetectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeLanguage(t *testing.T) {
+	for in, want := range map[string]string{"tr": "tr", " TR ": "tr", "Turkish": "tr", "arabic": "ar", "": ""} {
+		if got, err := NormalizeLanguage(in); err != nil || got != want {
+			t.Errorf("NormalizeLanguage(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	_, err := NormalizeLanguage("klingon")
+	if err == nil || !strings.Contains(err.Error(), "ar, de, en, es, fr, tr") {
+		t.Errorf("NormalizeLanguage(klingon) error = %v, want the supported codes", err)
+	}
+}
+
+func TestLanguageGuidelines(t *testing.T) {
+	if SimulatorLanguage("") != nil || JudgeLanguage("") != nil {
+		t.Error("an empty language produced guidelines")
+	}
+	sim, judge := SimulatorLanguage("tr"), JudgeLanguage("tr")
+	if sim.Name != "Turkish" || !strings.Contains(sim.Guidelines[0], "in Turkish") {
+		t.Errorf("SimulatorLanguage(tr) = %+v", sim)
+	}
+	// The judge writes its evidence in English whatever the conversation language
+	if judge.Name != "Turkish" || judge.Guidelines[len(judge.Guidelines)-1] != "Write evidence_summary in English." {
+		t.Errorf("JudgeLanguage(tr) = %+v", judge)
+	}
+}
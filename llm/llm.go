@@ -1,8 +1,11 @@
 package llm
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -12,9 +15,11 @@ var (
 	OpenAIProvider LLMProvider = "openai"
 	GeminiProvider LLMProvider = "gemini"
 	CohereProvider LLMProvider = "cohere"
+	MockProvider   LLMProvider = "mock" // Replays a script; no credentials needed
 )
 
 var (
+	// ContextTimeout bounds a single LLM call when the caller's context carries no deadline.
 	ContextTimeout = 90 * time.Second
 )
 
@@ -25,8 +30,42 @@ var (
 )
 
 type LLM interface {
-	GenerateContentREST(prompt string, input LLMInput) (*LLMOutput, error)
-	GenerateJudgmentREST(judgePrompt string, input JudgeInput) (*JudgmentResult, error)
+	GenerateContentREST(ctx context.Context, prompt string, input LLMInput, opts CallOptions) (*LLMOutput, error)
+	GenerateJudgmentREST(ctx context.Context, judgePrompt string, input JudgeInput, opts CallOptions) (*JudgmentResult, error)
+}
+
+// CallOptions tunes a single LLM call. Zero values fall back to the client's defaults,
+// so an empty CallOptions behaves exactly like the pre-configured client.
+type CallOptions struct {
+	Temperature float64 `json:"temperature"`
+	MaxTokens   int     `json:"max_tokens,omitempty"`
+	Seed        *int    `json:"seed,omitempty"`
+	Model       string  `json:"model,omitempty"` // Overrides the client's model for this call only
+}
+
+// model returns the per-call model override, or def when none is set.
+func (o CallOptions) model(def string) string {
+	if o.Model != "" {
+		return o.Model
+	}
+	return def
+}
+
+// maxTokens returns the per-call token limit, or def when none is set.
+func (o CallOptions) maxTokens(def int) int {
+	if o.MaxTokens > 0 {
+		return o.MaxTokens
+	}
+	return def
+}
+
+// callContext derives the context for a single LLM call. A deadline already set on ctx
+// (e.g. by the run configuration) is kept; otherwise ContextTimeout is applied.
+func callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, ContextTimeout)
 }
 
 type GeminiClient struct {
@@ -35,8 +74,15 @@ type GeminiClient struct {
 }
 
 type OpenAIClient struct {
-	apiKey string
-	Model  string
+	apiKey  string
+	Model   string
+	BaseURL string // API root such as "http://localhost:8000/v1"; empty means OpenAI
+}
+
+// NewOpenAICompatibleClient creates a client for any server implementing the OpenAI
+// /chat/completions API. apiKey may be empty for servers that don't require one.
+func NewOpenAICompatibleClient(baseURL, apiKey, model string) *OpenAIClient {
+	return &OpenAIClient{apiKey: apiKey, Model: model, BaseURL: strings.TrimSuffix(baseURL, "/")}
 }
 
 type CohereClient struct {
@@ -44,7 +90,43 @@ type CohereClient struct {
 	Model  string
 }
 
+// ParseProvider validates a provider name coming from a project or a run request.
+func ParseProvider(name string) (LLMProvider, error) {
+	switch p := LLMProvider(strings.ToLower(strings.TrimSpace(name))); p {
+	case OpenAIProvider, GeminiProvider, CohereProvider, MockProvider:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid provider: %s", name)
+}
+
+// DefaultModel returns the model used for a provider when none is configured.
+func DefaultModel(provider LLMProvider) string {
+	switch provider {
+	case OpenAIProvider:
+		return OpenAIModel
+	case GeminiProvider:
+		return GeminiModel
+	case CohereProvider:
+		return CohereModel
+	case MockProvider:
+		return MockModel
+	}
+	return ""
+}
+
+// ModelID formats a provider/model pair the way it is recorded on runs, e.g. "cohere/command-a-03-2025".
+func ModelID(provider LLMProvider, model string) string {
+	if model == "" {
+		model = DefaultModel(provider)
+	}
+	return string(provider) + "/" + model
+}
+
+// NewLLMClient creates a client for provider. An empty model selects DefaultModel(provider).
 func NewLLMClient(provider LLMProvider, model string) (LLM, error) {
+	if model == "" {
+		model = DefaultModel(provider)
+	}
 	switch provider {
 	case OpenAIProvider:
 		apiKey := os.Getenv("OPENAI_API_KEY")
@@ -53,7 +135,7 @@ func NewLLMClient(provider LLMProvider, model string) (LLM, error) {
 		}
 		return &OpenAIClient{
 			apiKey: apiKey,
-			Model:  OpenAIModel,
+			Model:  model,
 		}, nil
 	case GeminiProvider:
 		apiKey := os.Getenv("GEMINI_API_KEY")
@@ -62,7 +144,7 @@ func NewLLMClient(provider LLMProvider, model string) (LLM, error) {
 		}
 		return &GeminiClient{
 			apiKey: apiKey,
-			Model:  GeminiModel,
+			Model:  model,
 		}, nil
 	case CohereProvider:
 		apiKey := os.Getenv("COHERE_API_KEY")
@@ -71,8 +153,10 @@ func NewLLMClient(provider LLMProvider, model string) (LLM, error) {
 		}
 		return &CohereClient{
 			apiKey: apiKey,
-			Model:  CohereModel,
+			Model:  model,
 		}, nil
+	case MockProvider:
+		return NewMockClient(model)
 	default:
 		return nil, fmt.Errorf("invalid provider: %s", provider)
 	}
@@ -84,6 +168,8 @@ type LLMInput struct {
 	ExpectedOutcome string       `json:"expected_outcome"`
 	CurrentState    CurrentState `json:"current_state"`
 	Version         string       `json:"version"`
+	Persona         *Persona     `json:"persona,omitempty"`  // User the simulator plays; nil plays the generic tester
+	Language        *Language    `json:"language,omitempty"` // Language to converse in; nil leaves it to the prompt
 }
 
 // CurrentState defines the current state within the LLMInput.
@@ -99,6 +185,20 @@ type HistoryItem struct {
 	Turn      int16  `json:"turn"`
 	User      string `json:"user"`
 	Assistant string `json:"assistant"`
+
+	// AssistantMessages lists the VA's messages separately when it answered with several of
+	// them in one turn; Assistant then holds all of them joined.
+	AssistantMessages []string `json:"assistant_messages,omitempty"`
+
+	// VALanguage is the language code detected in the VA's reply, empty when undetected.
+	VALanguage string `json:"va_language,omitempty"`
+
+	// Activities is the VA's reply activities as JSON, persisted with the turn.
+	Activities json.RawMessage `json:"-"`
+
+	// Simulator is the LLM output that produced User. It is persisted with the turn but never
+	// sent back to the simulator or the judge.
+	Simulator *LLMOutput `json:"-"`
 }
 
 // LLMOutput defines the structure for the LLM's response, based on the specified schema.
@@ -111,11 +211,25 @@ type LLMOutput struct {
 	SafetyCheck     string   `json:"safety_check"`
 	ErrorLogs       []string `json:"error_logs"`
 	AdaptationNotes string   `json:"adaptation_notes"`
+
+	// Action chooses how the turn is delivered: ActionMessage sends NextMessage as typed text,
+	// ActionSelectOption clicks the option of the VA's last reply named by SelectedOption.
+	// Empty means ActionMessage.
+	Action         string `json:"action"`
+	SelectedOption string `json:"selected_option"`
 }
 
+// Simulator actions.
+const (
+	ActionMessage      = "message"
+	ActionSelectOption = "select_option"
+)
+
 type JudgeInput struct {
 	Scenario     string        `json:"scenario"`
 	Conversation []HistoryItem `json:"conversation"`
+	Persona      *Persona      `json:"persona,omitempty"`  // User the simulator played
+	Language     *Language     `json:"language,omitempty"` // Language the conversation was meant to be in
 }
 
 type JudgmentResult struct {
@@ -124,12 +238,48 @@ type JudgmentResult struct {
 	EvidenceSummary          string  `json:"evidence_summary"`
 	ScenarioCompletionScore  float64 `json:"scenario_completion_score"`
 	ConversationQualityScore float64 `json:"conversation_quality_score"`
-}
 
-func (c *OpenAIClient) GenerateJudgmentREST(judgePrompt string, input JudgeInput) (*JudgmentResult, error) {
-	return nil, fmt.Errorf("not implemented")
+	// Set only by a JudgePanel: the share of judges agreeing with Judgement and their outputs.
+	AgreementRate float64         `json:"agreement_rate,omitempty"`
+	Panel         []PanelJudgment `json:"panel,omitempty"`
 }
 
-func (c *GeminiClient) GenerateJudgmentREST(judgePrompt string, input JudgeInput) (*JudgmentResult, error) {
-	return nil, fmt.Errorf("not implemented")
+// Judgement verdicts produced by the judge prompt.
+const (
+	JudgementPass        = "Pass"
+	JudgementFail        = "Fail"
+	JudgementHumanReview = "Human_review"
+)
+
+// judgmentJSONSchema returns the JSON schema of JudgmentResult for providers that accept
+// standard JSON schema (OpenAI structured outputs).
+func judgmentJSONSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"judgment": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{JudgementPass, JudgementFail, JudgementHumanReview},
+				"description": "Final verdict on whether the scenario was completed as intended",
+			},
+			"confidence": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"high", "medium", "low"},
+				"description": "Level of confidence in the judgment",
+			},
+			"evidence_summary": map[string]interface{}{
+				"type":        "string",
+				"description": "Concise summary of evidence from the conversation that led to the verdict",
+			},
+			"scenario_completion_score": map[string]interface{}{
+				"type":        "number",
+				"description": "Score 0-1 for scenario completion",
+			},
+			"conversation_quality_score": map[string]interface{}{
+				"type":        "number",
+				"description": "Score 0-1 for overall conversation quality",
+			},
+		},
+		"required": []string{"judgment", "confidence", "evidence_summary", "scenario_completion_score", "conversation_quality_score"},
+	}
 }
@@ -0,0 +1,111 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// MockModel selects the built-in script of the mock provider. Any other model name is
+// the name of a MockScript JSON file in the mock scripts directory.
+const MockModel = "builtin"
+
+// DefaultMockScriptsDir is where mock scripts are read from when MOCK_SCRIPTS_DIR is not set.
+const DefaultMockScriptsDir = "./mock_scripts"
+
+// MockScript is a recorded simulator conversation replayed by MockClient.
+type MockScript struct {
+	Outputs  []LLMOutput     `json:"outputs"`            // One output per turn, in order
+	Judgment *JudgmentResult `json:"judgment,omitempty"` // Defaults to a Pass verdict
+}
+
+// MockClient is an LLM that replays a script instead of calling a provider, so runs are
+// deterministic and need no credentials. It is stateless: the output of a turn is chosen
+// by the turn number in the input, so one client can serve parallel runs.
+type MockClient struct {
+	Script MockScript
+	Model  string
+}
+
+// NewMockClient creates a mock LLM for model: MockModel (or empty) for the built-in
+// script, otherwise the name of a MockScript JSON file in MOCK_SCRIPTS_DIR. Names can't
+// contain directories, and ".json" is added when they have no extension.
+func NewMockClient(model string) (*MockClient, error) {
+	if model == "" || model == MockModel {
+		return &MockClient{Model: MockModel}, nil
+	}
+	name := model
+	if filepath.Base(name) != name || name == "." || name == ".." {
+		return nil, fmt.Errorf("invalid mock script name: %s", model)
+	}
+	if filepath.Ext(name) == "" {
+		name += ".json"
+	}
+	dir := os.Getenv("MOCK_SCRIPTS_DIR")
+	if dir == "" {
+		dir = DefaultMockScriptsDir
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mock script: %w", err)
+	}
+	var script MockScript
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("failed to parse mock script %s: %w", model, err)
+	}
+	return &MockClient{Script: script, Model: model}, nil
+}
+
+func (c *MockClient) GenerateContentREST(ctx context.Context, prompt string, input LLMInput, opts CallOptions) (*LLMOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	turn := int(input.CurrentState.TurnCount) // already counts the turn being generated
+	if len(c.Script.Outputs) == 0 {
+		return builtinMockOutput(turn, input.Scenario), nil
+	}
+	if turn >= 1 && turn <= len(c.Script.Outputs) {
+		out := c.Script.Outputs[turn-1]
+		return &out, nil
+	}
+	// The script ran out: end the conversation
+	return &LLMOutput{Fulfilled: true, Reasoning: "Mock script exhausted", Confidence: "high"}, nil
+}
+
+func (c *MockClient) GenerateJudgmentREST(ctx context.Context, judgePrompt string, input JudgeInput, opts CallOptions) (*JudgmentResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if c.Script.Judgment != nil {
+		result := *c.Script.Judgment
+		return &result, nil
+	}
+	return &JudgmentResult{
+		Judgement:                JudgementPass,
+		Confidence:               "high",
+		EvidenceSummary:          fmt.Sprintf("Mock judgment of a %d-turn conversation.", len(input.Conversation)),
+		ScenarioCompletionScore:  1,
+		ConversationQualityScore: 1,
+	}, nil
+}
+
+// builtinMockOutput is a three-turn conversation that states the scenario, asks a
+// follow-up question and ends.
+func builtinMockOutput(turn int, scenario string) *LLMOutput {
+	out := &LLMOutput{Confidence: "high", Strategy: "mock", SafetyCheck: "passed"}
+	switch turn {
+	case 1:
+		out.NextMessage = "Hello, I need help with the following: " + scenario
+		out.Reasoning = "Open the conversation with the scenario."
+	case 2:
+		out.NextMessage = "Can you give me more details?"
+		out.Reasoning = "Ask a follow-up question."
+	default:
+		out.NextMessage = "Thank you, that's all."
+		out.Reasoning = "Close the conversation."
+		out.Fulfilled = true
+	}
+	return out
+}
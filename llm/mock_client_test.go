@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewMockClientScriptNames(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("MOCK_SCRIPTS_DIR", dir)
+	script := `{"outputs": [{"next_message": "What is my balance?"}], "judgment": {"judgment": "Fail"}}`
+	for name, content := range map[string]string{"balance.json": script, "notes.txt": script, "broken.json": "{"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A script next to the directory must not be reachable through the name
+	if err := os.WriteFile(filepath.Join(filepath.Dir(dir), "outside.json"), []byte(script), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, model := range []string{"balance", "balance.json", "notes.txt"} {
+		client, err := NewMockClient(model)
+		if err != nil {
+			t.Errorf("NewMockClient(%q) error = %v", model, err)
+			continue
+		}
+		if len(client.Script.Outputs) != 1 || client.Model != model {
+			t.Errorf("NewMockClient(%q) = %+v", model, client)
+		}
+	}
+
+	for _, model := range []string{
+		"../outside.json",
+		filepath.Join(filepath.Dir(dir), "outside.json"),
+		"sub/balance.json",
+		"..",
+		".",
+		"missing",
+		"broken",
+	} {
+		if _, err := NewMockClient(model); err == nil {
+			t.Errorf("NewMockClient(%q) succeeded, want an error", model)
+		}
+	}
+
+	client, err := NewMockClient("")
+	if err != nil || client.Model != MockModel || len(client.Script.Outputs) != 0 {
+		t.Errorf("NewMockClient(\"\") = %+v, %v, want the built-in script", client, err)
+	}
+}
+
+func TestMockClientReplay(t *testing.T) {
+	client := &MockClient{Script: MockScript{
+		Outputs:  []LLMOutput{{NextMessage: "first"}, {NextMessage: "second"}},
+		Judgment: &JudgmentResult{Judgement: JudgementFail},
+	}}
+	ctx := context.Background()
+	// The turn number picks the output, so turns can be asked in any order
+	for _, tt := range []struct {
+		turn          int16
+		want          string
+		wantFulfilled bool
+	}{{2, "second", false}, {1, "first", false}, {3, "", true}, {0, "", true}} {
+		out, err := client.GenerateContentREST(ctx, "", LLMInput{CurrentState: CurrentState{TurnCount: tt.turn}}, CallOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.NextMessage != tt.want || out.Fulfilled != tt.wantFulfilled {
+			t.Errorf("turn %d = %q, fulfilled %t, want %q, %t", tt.turn, out.NextMessage, out.Fulfilled, tt.want, tt.wantFulfilled)
+		}
+	}
+
+	// Callers get a copy; changing it doesn't change later judgments
+	result, err := client.GenerateJudgmentREST(ctx, "", JudgeInput{}, CallOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result.Judgement = JudgementPass
+	if client.Script.Judgment.Judgement != JudgementFail {
+		t.Errorf("GenerateJudgmentREST() returned the script's own judgment")
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := client.GenerateContentREST(cancelled, "", LLMInput{}, CallOptions{}); err == nil {
+		t.Errorf("GenerateContentREST() ignored a cancelled context")
+	}
+}
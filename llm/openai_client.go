@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"time"
 )
 
@@ -15,8 +14,25 @@ import (
 type ChatCompletionRequest struct {
 	Model       string        `json:"model"`
 	Messages    []ChatMessage `json:"messages"`
-	Temperature float64       `json:"temperature,omitempty"`
+	Temperature float64       `json:"temperature"`
 	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Seed        *int          `json:"seed,omitempty"`
+	// ResponseFormat enables JSON mode or structured outputs; nil leaves the model unconstrained.
+	ResponseFormat *OpenAIResponseFormat `json:"response_format,omitempty"`
+}
+
+// OpenAIResponseFormat selects the output format of a chat completion.
+type OpenAIResponseFormat struct {
+	Type       string            `json:"type"` // "text", "json_object" or "json_schema"
+	JSONSchema *OpenAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+// OpenAIJSONSchema describes a structured-output schema. With Strict set, every property
+// must be listed in "required" and "additionalProperties" must be false.
+type OpenAIJSONSchema struct {
+	Name   string                 `json:"name"`
+	Strict bool                   `json:"strict"`
+	Schema map[string]interface{} `json:"schema"`
 }
 
 // ChatMessage defines a single message for the chat API.
@@ -34,18 +50,11 @@ type ChatCompletionResponse struct {
 }
 
 // GenerateContentREST interacts with the OpenAI Chat API via REST to generate content.
-func (c *OpenAIClient) GenerateContentREST(prompt string, input LLMInput) (*LLMOutput, error) {
+func (c *OpenAIClient) GenerateContentREST(ctx context.Context, prompt string, input LLMInput, opts CallOptions) (*LLMOutput, error) {
 	// Set up a context with a timeout
-	ctx, cancel := context.WithTimeout(context.Background(), ContextTimeout)
+	ctx, cancel := callContext(ctx)
 	defer cancel()
 
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY environment variable not set")
-	}
-
-	apiEndpoint := "https://api.openai.com/v1/chat/completions"
-
 	// Marshal input to JSON for user message
 	inputJSON, err := json.Marshal(input)
 	if err != nil {
@@ -61,14 +70,86 @@ func (c *OpenAIClient) GenerateContentREST(prompt string, input LLMInput) (*LLMO
 
 	// Build request body
 	reqBody := ChatCompletionRequest{
-		Model:       c.Model,
+		Model:       opts.model(c.Model),
 		Messages:    messages,
-		Temperature: 0,
-		MaxTokens:   1024,
+		Temperature: opts.Temperature,
+		MaxTokens:   opts.maxTokens(1024),
+		Seed:        opts.Seed,
+	}
+	raw, err := c.ChatCompletion(ctx, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var output LLMOutput
+	if err := json.Unmarshal([]byte(raw), &output); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal LLMOutput from OpenAI response: %w. Raw content: %s", err, raw)
+	}
+
+	return &output, nil
+}
+
+// GenerateJudgmentREST asks the OpenAI model for a final verdict using structured outputs,
+// so the response is guaranteed to match JudgmentResult.
+func (c *OpenAIClient) GenerateJudgmentREST(ctx context.Context, judgePrompt string, input JudgeInput, opts CallOptions) (*JudgmentResult, error) {
+	ctx, cancel := callContext(ctx)
+	defer cancel()
+
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JudgeInput: %w", err)
 	}
+
+	schema := judgmentJSONSchema()
+	schema["additionalProperties"] = false
+
+	reqBody := ChatCompletionRequest{
+		Model: opts.model(c.Model),
+		Messages: []ChatMessage{
+			{Role: "system", Content: judgePrompt},
+			{Role: "user", Content: string(inputJSON)},
+		},
+		Temperature: opts.Temperature,
+		MaxTokens:   opts.maxTokens(1024),
+		Seed:        opts.Seed,
+		ResponseFormat: &OpenAIResponseFormat{
+			Type: "json_schema",
+			JSONSchema: &OpenAIJSONSchema{
+				Name:   "judgment_result",
+				Strict: true,
+				Schema: schema,
+			},
+		},
+	}
+	raw, err := c.ChatCompletion(ctx, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var result JudgmentResult
+	if err := json.Unmarshal([]byte(raw), &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JudgmentResult from OpenAI response: %w. Raw content: %s", err, raw)
+	}
+
+	return &result, nil
+}
+
+// ChatCompletion posts reqBody to the chat completions endpoint with retries and
+// returns the content of the first choice.
+func (c *OpenAIClient) ChatCompletion(ctx context.Context, reqBody ChatCompletionRequest) (string, error) {
+	apiKey := c.apiKey
+	if apiKey == "" && c.BaseURL == "" {
+		return "", fmt.Errorf("OPENAI_API_KEY environment variable not set")
+	}
+
+	apiEndpoint := "https://api.openai.com/v1/chat/completions"
+	if c.BaseURL != "" {
+		apiEndpoint = c.BaseURL + "/chat/completions"
+	}
+
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		return "", fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
 	// Create HTTP client
@@ -89,10 +170,12 @@ func (c *OpenAIClient) GenerateContentREST(prompt string, input LLMInput) (*LLMO
 		// Prepare request
 		req, err := http.NewRequestWithContext(ctx, "POST", apiEndpoint, bytes.NewBuffer(jsonBody))
 		if err != nil {
-			return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+			return "", fmt.Errorf("failed to create HTTP request: %w", err)
 		}
 		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", "Bearer "+apiKey)
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
 
 		resp, err = client.Do(req)
 		if err == nil && resp != nil && resp.StatusCode == http.StatusOK {
@@ -111,7 +194,7 @@ func (c *OpenAIClient) GenerateContentREST(prompt string, input LLMInput) (*LLMO
 
 		shouldRetry := false
 		if ctx.Err() != nil { // Overall context cancelled
-			return nil, fmt.Errorf("OpenAI request cancelled or timed out during retry: %w", ctx.Err())
+			return "", fmt.Errorf("OpenAI request cancelled or timed out during retry: %w", ctx.Err())
 		}
 
 		if err != nil { // Network error or client timeout from client.Do(req)
@@ -146,11 +229,11 @@ func (c *OpenAIClient) GenerateContentREST(prompt string, input LLMInput) (*LLMO
 
 	// After the loop, check the outcome
 	if ctx.Err() != nil { // Check overall context timeout first
-		return nil, fmt.Errorf("OpenAI operation cancelled or timed out after %d attempts. Last error: %v", maxRetries, lastErr)
+		return "", fmt.Errorf("OpenAI operation cancelled or timed out after %d attempts. Last error: %v", maxRetries, lastErr)
 	}
 
 	if resp == nil { // All attempts failed, possibly due to network errors
-		return nil, fmt.Errorf("all %d OpenAI request attempts failed. Last error: %w", maxRetries, lastErr)
+		return "", fmt.Errorf("all %d OpenAI request attempts failed. Last error: %w", maxRetries, lastErr)
 	}
 	// If we are here, resp is not nil. We must close its body.
 	// If it was closed in the loop (for retriable errors), closing again is fine.
@@ -159,36 +242,31 @@ func (c *OpenAIClient) GenerateContentREST(prompt string, input LLMInput) (*LLMO
 	if resp.StatusCode != http.StatusOK {
 		body, readErr := io.ReadAll(resp.Body) // Read body for error message
 		if readErr != nil {
-			return nil, fmt.Errorf("OpenAI API error: status %d, failed to read error body: %w", resp.StatusCode, readErr)
+			return "", fmt.Errorf("OpenAI API error: status %d, failed to read error body: %w", resp.StatusCode, readErr)
 		}
-		return nil, fmt.Errorf("OpenAI API error: status %d, body: %s", resp.StatusCode, string(body))
+		return "", fmt.Errorf("OpenAI API error: status %d, body: %s", resp.StatusCode, string(body))
 	}
 
 	respBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return "", fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	var chatResp ChatCompletionResponse
 	if err := json.Unmarshal(respBytes, &chatResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal chat response: %w", err)
+		return "", fmt.Errorf("failed to unmarshal chat response: %w", err)
 	}
 
 	if len(chatResp.Choices) == 0 {
-		return nil, fmt.Errorf("no choices returned from API")
+		return "", fmt.Errorf("no choices returned from API")
 	}
 
 	raw := chatResp.Choices[0].Message.Content
 	if raw == "" {
 		// Similar to Gemini client, if the content string is empty,
 		// unmarshalling it would lead to zero-values, which might be misleading.
-		return nil, fmt.Errorf("OpenAI API returned empty message content")
+		return "", fmt.Errorf("OpenAI API returned empty message content")
 	}
 
-	var output LLMOutput
-	if err := json.Unmarshal([]byte(raw), &output); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal LLMOutput from OpenAI response: %w. Raw content: %s", err, raw)
-	}
-
-	return &output, nil
+	return raw, nil
 }
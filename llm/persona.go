@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Persona trait values. Empty traits leave the behaviour to the simulator prompt.
+var (
+	PersonaPatience  = []string{"low", "medium", "high"}
+	PersonaExpertise = []string{"novice", "intermediate", "expert"}
+	PersonaTypos     = []string{"none", "occasional", "frequent"}
+	PersonaVerbosity = []string{"terse", "normal", "verbose"}
+)
+
+// Persona is the user the simulator plays. It is sent in LLMInput and JudgeInput with
+// Guidelines spelling out the traits, so it also works with prompts that don't describe it.
+// A persona's language is sent separately, as the run's Language.
+type Persona struct {
+	Name               string   `json:"name"`
+	Description        string   `json:"description,omitempty"`
+	Tone               string   `json:"tone,omitempty"` // e.g. "polite", "frustrated"
+	Patience           string   `json:"patience,omitempty"`
+	Expertise          string   `json:"expertise,omitempty"`
+	Typos              string   `json:"typos,omitempty"`
+	Verbosity          string   `json:"verbosity,omitempty"`
+	AccessibilityNeeds string   `json:"accessibility_needs,omitempty"`
+	Instructions       string   `json:"instructions,omitempty"` // Free-form additions
+	Guidelines         []string `json:"guidelines,omitempty"`
+}
+
+// ValidatePersonaTrait checks that value is empty or one of allowed.
+func ValidatePersonaTrait(trait, value string, allowed []string) error {
+	if value == "" {
+		return nil
+	}
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s must be one of %s", trait, strings.Join(allowed, ", "))
+}
+
+// WithGuidelines returns a copy of the persona with Guidelines describing how to play it.
+// It returns nil for a nil persona.
+func (p *Persona) WithGuidelines() *Persona {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	c.Guidelines = nil
+	add := func(format string, args ...any) { c.Guidelines = append(c.Guidelines, fmt.Sprintf(format, args...)) }
+
+	add("Play this user for the whole conversation; it overrides the default tester behaviour, not the scenario goal or the safety rules.")
+	if c.Description != "" {
+		add("Who you are: %s", c.Description)
+	}
+	if c.Tone != "" {
+		add("Your tone is %s.", c.Tone)
+	}
+	switch c.Patience {
+	case "low":
+		add("You are impatient: if the VA misunderstands or repeats itself, say so, and ask for a human agent after a second failed attempt.")
+	case "medium":
+		add("You tolerate one or two misunderstandings before showing annoyance.")
+	case "high":
+		add("You are patient: rephrase calmly as often as needed.")
+	}
+	switch c.Expertise {
+	case "novice":
+		add("You don't know the product's terms; describe what you want in everyday words and ask what unfamiliar terms mean.")
+	case "intermediate":
+		add("You know the common terms but not the details of the product.")
+	case "expert":
+		add("You know the domain well; use precise terms and expect precise answers.")
+	}
+	switch c.Typos {
+	case "none":
+		add("Write without typos.")
+	case "occasional":
+		add("Make an occasional typo or missing capital, like someone typing quickly.")
+	case "frequent":
+		add("Make frequent typos, skip punctuation and use abbreviations, like someone typing fast on a phone.")
+	}
+	switch c.Verbosity {
+	case "terse":
+		add("Keep messages to a few words.")
+	case "normal":
+		add("Write messages of one or two sentences.")
+	case "verbose":
+		add("Write long messages with background details that are not all relevant.")
+	}
+	if c.AccessibilityNeeds != "" {
+		add("Accessibility needs: %s. Behave accordingly and note in adaptation_notes when the VA does not accommodate them.", c.AccessibilityNeeds)
+	}
+	if c.Instructions != "" {
+		add("%s", c.Instructions)
+	}
+	return &c
+}
@@ -1,5 +1,18 @@
 package llm
 
+// Prompt kinds of the prompt registry.
+const (
+	PromptKindSimulator = "simulator"
+	PromptKindJudge     = "judge"
+)
+
+// The compiled-in SystemPrompt and JudgePrompt are registered as BuiltinPromptName at
+// BuiltinPromptVersion. Projects that select no prompt use them.
+const (
+	BuiltinPromptName    = "builtin"
+	BuiltinPromptVersion = "2.0"
+)
+
 var SystemPrompt = `
 ## Your Role and Identity
 
@@ -52,7 +65,7 @@ json
   "current_state": {
     "history": [
       {"turn": 1, "user": "your previous message", "assistant": "VA response"},
-      {"turn": 2, "user": "your message", "assistant": "VA response"}
+      {"turn": 2, "user": "your message", "assistant": "VA response", "assistant_messages": ["first VA message", "follow-up VA message"]}
     ],
     "turn_count": 2,
     "max_turns": 10,
@@ -61,6 +74,12 @@ json
   "version": "prompt_version_identifier"
 }
 
+A history item with turn 0 and an empty "user" is the VA's greeting, sent before you spoke; its options can be selected like any other.
+
+When the VA answered with several messages in one turn, "assistant_messages" lists them in order and "assistant" holds all of them. Read every message: the last one often carries the follow-up question or the choices you must answer.
+
+Buttons, cards and suggested actions are listed under "Options:" as "  - title (value: value)". When a real user would tap one of them rather than type, set "action" to "select_option" and "selected_option" to its exact title or value; the button is then pressed the way a real user would press it. Set "next_message" to the option's title so the transcript stays readable.
+
 
 ## Decision Framework
 
@@ -91,7 +110,9 @@ json
   "strategy": "direct/exploratory/clarification/escalation/alternative",
   "safety_check": "passed/flagged",
   "error_logs": ["any unexpected behaviors or responses to log"],
-  "adaptation_notes": "how you're adapting based on VA patterns"
+  "adaptation_notes": "how you're adapting based on VA patterns",
+  "action": "message/select_option",
+  "selected_option": "title or value of the option to click, empty for a typed message"
 }
 
 
@@ -104,6 +125,8 @@ json
 - **safety_check**: "passed" for normal interactions, "flagged" if concerning behavior detected
 - **error_logs**: Array of any unexpected VA behaviors, errors, or concerning responses
 - **adaptation_notes**: How you're modifying your approach based on learned VA patterns
+- **action**: "message" to type next_message, "select_option" to click one of the options offered in the VA's last reply
+- **selected_option**: The exact title or value of the option to click when action is "select_option"; otherwise ""
 
 ## Behavioral Guidelines
 
@@ -219,7 +242,9 @@ json
   "strategy": "direct",
   "safety_check": "passed",
   "error_logs": [],
-  "adaptation_notes": "Testing initial response capability for booking requests"
+  "adaptation_notes": "Testing initial response capability for booking requests",
+  "action": "message",
+  "selected_option": ""
 }
 
 
@@ -233,7 +258,25 @@ json
   "strategy": "direct",
   "safety_check": "passed",
   "error_logs": [],
-  "adaptation_notes": "VA responds well to specific room requests, continuing with direct approach"
+  "adaptation_notes": "VA responds well to specific room requests, continuing with direct approach",
+  "action": "message",
+  "selected_option": ""
+}
+
+
+**Option Selection Example** (the VA offered "Options:\n  - Conference Room A (value: room_a)\n  - Conference Room B (value: room_b)"):
+json
+{
+  "next_message": "Conference Room A",
+  "reasoning": "The VA offers the rooms as buttons, so I tap the one I want like a real user",
+  "fulfilled": false,
+  "confidence": "high",
+  "strategy": "direct",
+  "safety_check": "passed",
+  "error_logs": [],
+  "adaptation_notes": "VA drives room selection through buttons",
+  "action": "select_option",
+  "selected_option": "room_a"
 }
 
 
@@ -247,7 +290,9 @@ json
   "strategy": "direct",
   "safety_check": "passed",
   "error_logs": [],
-  "adaptation_notes": "Successful completion using direct approach - pattern noted for similar scenarios"
+  "adaptation_notes": "Successful completion using direct approach - pattern noted for similar scenarios",
+  "action": "message",
+  "selected_option": ""
 }
 
 
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"evaluator/cassette"
 	"evaluator/db"
 	"evaluator/handlers" // New import
+	"flag"
 
 	"log"
 	"net/http"
@@ -13,6 +15,11 @@ import (
 )
 
 func main() {
+	offline := flag.Bool("mock", false, "run every scenario against the built-in mock target and mock LLM; no Knovvu or LLM credentials are needed")
+	record := flag.String("record", "", "record all LLM and target traffic to this cassette file")
+	replay := flag.String("replay", "", "serve all LLM and target traffic from this cassette file instead of the network")
+	flag.Parse()
+
 	err := godotenv.Load(".env")
 	if err != nil {
 		log.Printf("Warning: Error loading .env file: %v", err)
@@ -25,8 +32,35 @@ func main() {
 	}
 	defer dbConn.Close()
 
+	// Bring the schema up to date before serving requests
+	if err := db.Migrate(dbConn); err != nil {
+		log.Fatalf("Error migrating database: %v", err)
+	}
+
+	// Runs may attach their own cassette; -record/-replay set one for all other traffic
+	var defaultCassette *cassette.Cassette
+	if *record != "" && *replay != "" {
+		log.Fatalf("-record and -replay cannot be used together")
+	}
+	if *record != "" {
+		defaultCassette, err = cassette.Open(*record, cassette.ModeRecord)
+	} else if *replay != "" {
+		defaultCassette, err = cassette.Open(*replay, cassette.ModeReplay)
+	}
+	if err != nil {
+		log.Fatalf("Error opening cassette: %v", err)
+	}
+	if defaultCassette != nil {
+		log.Printf("Cassette %s mode: %s", defaultCassette.Mode, defaultCassette.Path)
+	}
+	cassette.Install(defaultCassette)
+
 	// Initialize the API environment with dependencies
 	apiEnv := handlers.NewAPIEnv(dbConn)
+	apiEnv.Offline = *offline
+	if *offline {
+		log.Println("Offline mode: runs use the mock target and mock LLM")
+	}
 
 	// --- HTTP API Server ---
 	// The TestRepo, ScenarioRepo etc. are now initialized within NewAPIEnv and accessed via apiEnv.
@@ -55,6 +89,12 @@ func main() {
 			apiEnv.StopScenarioHandler(w, r)
 		} else if strings.HasSuffix(r.URL.Path, "/runs") && r.Method == "GET" {
 			apiEnv.GetTestRunsByScenarioHandler(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/personas") {
+			apiEnv.ScenarioPersonasHandler(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/language") {
+			apiEnv.ScenarioLanguageHandler(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/data") {
+			apiEnv.ScenarioDataHandler(w, r)
 		} else {
 			// fallback for other /scenarios/ endpoints
 			http.NotFound(w, r)
@@ -65,6 +105,17 @@ func main() {
 	http.HandleFunc("/api/interactions/", apiEnv.ListInteractionsByTestRunHandler)
 	http.HandleFunc("/api/interactions", apiEnv.CreateInteractionHandler)
 
+	// Handle /api/judgments/{testRunID} (GET): individual judge panel verdicts
+	http.HandleFunc("/api/judgments/", apiEnv.ListJudgmentsByTestRunHandler)
+
+	// Prompt registry: /api/prompts (list, create) and /api/prompts/{id} (get, update, delete)
+	http.HandleFunc("/api/prompts", apiEnv.PromptsHandler)
+	http.HandleFunc("/api/prompts/", apiEnv.PromptHandler)
+
+	// Persona library: /api/personas (list, create) and /api/personas/{id} (get, update, delete)
+	http.HandleFunc("/api/personas", apiEnv.PersonasHandler)
+	http.HandleFunc("/api/personas/", apiEnv.PersonaHandler)
+
 	// --- Logging for registered routes (optional, for verification) ---
 	log.Println("Registered route: GET, POST /projects")
 	log.Println("Registered route: (various) /projects/*")
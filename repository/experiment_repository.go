@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"database/sql"
+)
+
+// ExperimentRepo stores experiments that run a project's scenarios under several prompt or
+// model variants. The runs themselves live in runs, tagged with experiment_id and variant.
+type ExperimentRepo interface {
+	Create(testID int, name, config string) (int, error)
+	GetByID(experimentID int) (*Experiment, error)
+	ListByTest(testID int) ([]Experiment, error)
+	UpdateStatus(experimentID int, status string) error
+	GetRuns(experimentID int) ([]ExperimentRun, error)
+}
+
+type Experiment struct {
+	ID          int     `json:"id"`
+	TestID      int     `json:"project_id"`
+	Name        string  `json:"name"`
+	Status      string  `json:"status"`           // "running", "completed", "cancelled", "timed_out" or "failed"
+	Config      string  `json:"config,omitempty"` // Resolved variants and repetitions, as JSON
+	CreatedAt   string  `json:"created_at"`
+	CompletedAt *string `json:"completed_at"`
+}
+
+// ExperimentRun is the outcome of one scenario run of an experiment variant.
+type ExperimentRun struct {
+	RunID                    int
+	ScenarioID               int
+	Variant                  string
+	Status                   string
+	Verdict                  string
+	Judged                   bool // Whether a judge produced a verdict; scores are 0 otherwise
+	ScenarioCompletionScore  float64
+	ConversationQualityScore float64
+	Turns                    int // Simulator turns, not counting the VA greeting
+}
+
+type ExperimentRepository struct {
+	db *sql.DB
+}
+
+func NewExperimentRepository(db *sql.DB) ExperimentRepo {
+	return &ExperimentRepository{db: db}
+}
+
+const experimentColumns = "id, test_id, name, status, config, created_at, completed_at"
+
+func scanExperiment(row interface{ Scan(dest ...any) error }) (*Experiment, error) {
+	var e Experiment
+	var completedAt sql.NullString
+	if err := row.Scan(&e.ID, &e.TestID, &e.Name, &e.Status, &e.Config, &e.CreatedAt, &completedAt); err != nil {
+		return nil, err
+	}
+	if completedAt.Valid {
+		e.CompletedAt = &completedAt.String
+	}
+	return &e, nil
+}
+
+func (r *ExperimentRepository) Create(testID int, name, config string) (int, error) {
+	res, err := r.db.Exec(`INSERT INTO experiments (test_id, name, status, config) VALUES (?, ?, 'running', ?)`, testID, name, config)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (r *ExperimentRepository) GetByID(experimentID int) (*Experiment, error) {
+	e, err := scanExperiment(r.db.QueryRow(`SELECT `+experimentColumns+` FROM experiments WHERE id = ?`, experimentID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return e, err
+}
+
+// ListByTest returns a project's experiments, newest first.
+func (r *ExperimentRepository) ListByTest(testID int) ([]Experiment, error) {
+	rows, err := r.db.Query(`SELECT `+experimentColumns+` FROM experiments WHERE test_id = ? ORDER BY id DESC`, testID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var experiments []Experiment
+	for rows.Next() {
+		e, err := scanExperiment(rows)
+		if err != nil {
+			return nil, err
+		}
+		experiments = append(experiments, *e)
+	}
+	return experiments, rows.Err()
+}
+
+// UpdateStatus sets the experiment's status, stamping completed_at once it stops running.
+func (r *ExperimentRepository) UpdateStatus(experimentID int, status string) error {
+	_, err := r.db.Exec(`UPDATE experiments SET status = ?, completed_at = CASE WHEN ? = 'running' THEN NULL ELSE CURRENT_TIMESTAMP END WHERE id = ?`,
+		status, status, experimentID)
+	return err
+}
+
+// GetRuns returns the runs of an experiment in the order they were started.
+func (r *ExperimentRepository) GetRuns(experimentID int) ([]ExperimentRun, error) {
+	query := `SELECT runs.id, runs.scenario_id, runs.variant, runs.status, COALESCE(runs.verdict, ''),
+		EXISTS (SELECT 1 FROM run_judgments WHERE run_judgments.run_id = runs.id),
+		COALESCE(runs.scenario_completion_score, 0), COALESCE(runs.conversation_quality_score, 0),
+		(SELECT COUNT(*) FROM interactions WHERE interactions.run_id = runs.id AND interactions.turn_number > 0)
+		FROM runs WHERE runs.experiment_id = ? ORDER BY runs.id ASC`
+	rows, err := r.db.Query(query, experimentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []ExperimentRun
+	for rows.Next() {
+		var er ExperimentRun
+		if err := rows.Scan(&er.RunID, &er.ScenarioID, &er.Variant, &er.Status, &er.Verdict, &er.Judged,
+			&er.ScenarioCompletionScore, &er.ConversationQualityScore, &er.Turns); err != nil {
+			return nil, err
+		}
+		runs = append(runs, er)
+	}
+	return runs, rows.Err()
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 )
 
 type InteractionRepo interface {
@@ -14,10 +15,28 @@ type Interaction struct {
 	TestRunID           int
 	ScenarioID          int
 	TurnNumber          int
+	Persona             string // Persona the simulator played; empty for the generic tester
+	DataRow             int    // Data table row of the scenario case; 0 without a data table
 	UserMessage         string
 	LLMResponse         string
+	VALanguage          string // Language code detected in LLMResponse; empty when undetected
 	EvaluationResult    string
 	EvaluationReasoning string
+
+	// Simulator metadata of the turn, as returned by the LLM that wrote UserMessage
+	Fulfilled       bool
+	Reasoning       string
+	Strategy        string
+	Confidence      string
+	SafetyCheck     string
+	ErrorLogs       []string
+	AdaptationNotes string
+	Action          string // "message" or "select_option"
+	SelectedOption  string
+
+	// VAActivities holds every activity of the VA's reply in order, as a JSON array. LLMResponse
+	// is the rendered text of its messages.
+	VAActivities json.RawMessage
 }
 
 type InteractionRepository struct {
@@ -29,13 +48,26 @@ func NewInteractionRepository(db *sql.DB) InteractionRepo {
 }
 
 func (r *InteractionRepository) Create(interaction *Interaction) error {
-	query := `INSERT INTO interactions (run_id, scenario_id, turn_number, user_message, llm_response, evaluation_result, evaluation_reasoning) VALUES (?, ?, ?, ?, ?, ?, ?)`
-	_, err := r.db.Exec(query, interaction.TestRunID, interaction.ScenarioID, interaction.TurnNumber, interaction.UserMessage, interaction.LLMResponse, interaction.EvaluationResult, interaction.EvaluationReasoning)
-	return err
+	errorLogs, err := json.Marshal(interaction.ErrorLogs)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO interactions (run_id, scenario_id, turn_number, user_message, llm_response, evaluation_result, evaluation_reasoning, fulfilled, reasoning, strategy, confidence, safety_check, error_logs, adaptation_notes, va_activities, action, selected_option, persona, va_language, data_row) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := r.db.Exec(query, interaction.TestRunID, interaction.ScenarioID, interaction.TurnNumber, interaction.UserMessage, interaction.LLMResponse, interaction.EvaluationResult, interaction.EvaluationReasoning,
+		interaction.Fulfilled, interaction.Reasoning, interaction.Strategy, interaction.Confidence, interaction.SafetyCheck, string(errorLogs), interaction.AdaptationNotes, string(interaction.VAActivities), interaction.Action, interaction.SelectedOption, interaction.Persona, interaction.VALanguage, interaction.DataRow)
+	if err != nil {
+		return err
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		interaction.ID = int(id)
+	}
+	return nil
 }
 
 func (r *InteractionRepository) GetByTestRunID(testRunID int) ([]Interaction, error) {
-	query := `SELECT id, run_id, scenario_id, turn_number, user_message, llm_response, evaluation_result, evaluation_reasoning FROM interactions WHERE run_id = ?`
+	query := `SELECT id, run_id, scenario_id, turn_number, user_message, llm_response, COALESCE(evaluation_result, ''), COALESCE(evaluation_reasoning, ''),
+		COALESCE(fulfilled, 0), COALESCE(reasoning, ''), COALESCE(strategy, ''), COALESCE(confidence, ''), COALESCE(safety_check, ''), COALESCE(error_logs, ''), COALESCE(adaptation_notes, ''), COALESCE(va_activities, ''), COALESCE(action, ''), COALESCE(selected_option, ''), persona, va_language, data_row
+		FROM interactions WHERE run_id = ? ORDER BY id ASC`
 	rows, err := r.db.Query(query, testRunID)
 	if err != nil {
 		return nil, err
@@ -45,9 +77,18 @@ func (r *InteractionRepository) GetByTestRunID(testRunID int) ([]Interaction, er
 	var interactions []Interaction
 	for rows.Next() {
 		var i Interaction
-		if err := rows.Scan(&i.ID, &i.TestRunID, &i.ScenarioID, &i.TurnNumber, &i.UserMessage, &i.LLMResponse, &i.EvaluationResult, &i.EvaluationReasoning); err != nil {
+		var errorLogs, activities string
+		if err := rows.Scan(&i.ID, &i.TestRunID, &i.ScenarioID, &i.TurnNumber, &i.UserMessage, &i.LLMResponse, &i.EvaluationResult, &i.EvaluationReasoning,
+			&i.Fulfilled, &i.Reasoning, &i.Strategy, &i.Confidence, &i.SafetyCheck, &errorLogs, &i.AdaptationNotes, &activities, &i.Action, &i.SelectedOption, &i.Persona, &i.VALanguage, &i.DataRow); err != nil {
 			return nil, err
 		}
+		if activities != "" {
+			i.VAActivities = json.RawMessage(activities)
+		}
+		if errorLogs != "" {
+			// Rows written before error logs were stored as JSON are left empty
+			_ = json.Unmarshal([]byte(errorLogs), &i.ErrorLogs)
+		}
 		interactions = append(interactions, i)
 	}
 	return interactions, nil
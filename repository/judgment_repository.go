@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"database/sql"
+)
+
+// JudgmentRepo stores the individual verdicts of a judge panel.
+type JudgmentRepo interface {
+	Create(judgment *Judgment) error
+	GetByTestRunID(testRunID int) ([]Judgment, error)
+}
+
+type Judgment struct {
+	ID                       int
+	TestRunID                int
+	ScenarioID               int
+	Persona                  string // Persona the simulator played; empty for the generic tester
+	DataRow                  int    // Data table row of the scenario case; 0 without a data table
+	JudgeModel               string
+	Judgment                 string
+	Confidence               string
+	EvidenceSummary          string
+	ScenarioCompletionScore  float64
+	ConversationQualityScore float64
+	Error                    string // Set when the judge failed to produce a verdict
+}
+
+type JudgmentRepository struct {
+	db *sql.DB
+}
+
+func NewJudgmentRepository(db *sql.DB) JudgmentRepo {
+	return &JudgmentRepository{db: db}
+}
+
+func (r *JudgmentRepository) Create(j *Judgment) error {
+	query := `INSERT INTO run_judgments (run_id, scenario_id, judge_model, judgment, confidence, evidence_summary, scenario_completion_score, conversation_quality_score, error, persona, data_row) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := r.db.Exec(query, j.TestRunID, j.ScenarioID, j.JudgeModel, j.Judgment, j.Confidence, j.EvidenceSummary, j.ScenarioCompletionScore, j.ConversationQualityScore, j.Error, j.Persona, j.DataRow)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	j.ID = int(id)
+	return nil
+}
+
+func (r *JudgmentRepository) GetByTestRunID(testRunID int) ([]Judgment, error) {
+	query := `SELECT id, run_id, scenario_id, judge_model, judgment, confidence, evidence_summary, scenario_completion_score, conversation_quality_score, error, persona, data_row FROM run_judgments WHERE run_id = ? ORDER BY id ASC`
+	rows, err := r.db.Query(query, testRunID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var judgments []Judgment
+	for rows.Next() {
+		var j Judgment
+		if err := rows.Scan(&j.ID, &j.TestRunID, &j.ScenarioID, &j.JudgeModel, &j.Judgment, &j.Confidence, &j.EvidenceSummary, &j.ScenarioCompletionScore, &j.ConversationQualityScore, &j.Error, &j.Persona, &j.DataRow); err != nil {
+			return nil, err
+		}
+		judgments = append(judgments, j)
+	}
+	return judgments, rows.Err()
+}
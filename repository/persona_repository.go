@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPersonaInUse is returned when deleting a persona that a project or scenario still selects.
+var ErrPersonaInUse = errors.New("persona is selected by a project or scenario")
+
+// PersonaRepo stores the persona library. Projects and scenarios refer to personas by name.
+type PersonaRepo interface {
+	Create(persona *Persona) error
+	GetByID(personaID int) (*Persona, error)
+	GetByName(name string) (*Persona, error)
+	List() ([]Persona, error)
+	Update(persona *Persona) error
+	Delete(personaID int) error
+}
+
+type Persona struct {
+	ID                 int    `json:"id"`
+	Name               string `json:"name"`
+	Description        string `json:"description"`
+	Language           string `json:"language"` // Conversation language code, e.g. "tr"
+	Tone               string `json:"tone"`
+	Patience           string `json:"patience"`  // "low", "medium" or "high"
+	Expertise          string `json:"expertise"` // "novice", "intermediate" or "expert"
+	Typos              string `json:"typos"`     // "none", "occasional" or "frequent"
+	Verbosity          string `json:"verbosity"` // "terse", "normal" or "verbose"
+	AccessibilityNeeds string `json:"accessibility_needs"`
+	Instructions       string `json:"instructions"`
+	CreatedAt          string `json:"created_at"`
+}
+
+type PersonaRepository struct {
+	db *sql.DB
+}
+
+func NewPersonaRepository(db *sql.DB) PersonaRepo {
+	return &PersonaRepository{db: db}
+}
+
+const personaColumns = "id, name, description, language, tone, patience, expertise, typos, verbosity, accessibility_needs, instructions, created_at"
+
+func scanPersona(row interface{ Scan(dest ...any) error }) (*Persona, error) {
+	var p Persona
+	if err := row.Scan(&p.ID, &p.Name, &p.Description, &p.Language, &p.Tone, &p.Patience, &p.Expertise, &p.Typos, &p.Verbosity,
+		&p.AccessibilityNeeds, &p.Instructions, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *PersonaRepository) Create(p *Persona) error {
+	res, err := r.db.Exec(`INSERT INTO personas (name, description, language, tone, patience, expertise, typos, verbosity, accessibility_needs, instructions) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.Name, p.Description, p.Language, p.Tone, p.Patience, p.Expertise, p.Typos, p.Verbosity, p.AccessibilityNeeds, p.Instructions)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return fmt.Errorf("persona %q already exists", p.Name)
+		}
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	created, err := r.GetByID(int(id))
+	if err != nil {
+		return err
+	}
+	*p = *created
+	return nil
+}
+
+func (r *PersonaRepository) GetByID(personaID int) (*Persona, error) {
+	p, err := scanPersona(r.db.QueryRow(`SELECT `+personaColumns+` FROM personas WHERE id = ?`, personaID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+func (r *PersonaRepository) GetByName(name string) (*Persona, error) {
+	p, err := scanPersona(r.db.QueryRow(`SELECT `+personaColumns+` FROM personas WHERE name = ?`, name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+func (r *PersonaRepository) List() ([]Persona, error) {
+	rows, err := r.db.Query(`SELECT ` + personaColumns + ` FROM personas ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var personas []Persona
+	for rows.Next() {
+		p, err := scanPersona(rows)
+		if err != nil {
+			return nil, err
+		}
+		personas = append(personas, *p)
+	}
+	return personas, rows.Err()
+}
+
+// Update replaces the traits of a persona. Its name can't change, as projects and scenarios refer to it.
+func (r *PersonaRepository) Update(p *Persona) error {
+	_, err := r.db.Exec(`UPDATE personas SET description = ?, language = ?, tone = ?, patience = ?, expertise = ?, typos = ?, verbosity = ?, accessibility_needs = ?, instructions = ? WHERE id = ?`,
+		p.Description, p.Language, p.Tone, p.Patience, p.Expertise, p.Typos, p.Verbosity, p.AccessibilityNeeds, p.Instructions, p.ID)
+	return err
+}
+
+// Delete removes a persona unless a project or scenario selects it.
+func (r *PersonaRepository) Delete(personaID int) error {
+	p, err := r.GetByID(personaID)
+	if err != nil || p == nil {
+		return err
+	}
+	var uses int
+	query := `SELECT (SELECT COUNT(*) FROM tests, json_each(NULLIF(tests.personas, '')) WHERE json_each.value = ?)
+		+ (SELECT COUNT(*) FROM scenarios, json_each(NULLIF(scenarios.personas, '')) WHERE json_each.value = ?)`
+	if err := r.db.QueryRow(query, p.Name, p.Name).Scan(&uses); err != nil {
+		return err
+	}
+	if uses > 0 {
+		return ErrPersonaInUse
+	}
+	_, err = r.db.Exec(`DELETE FROM personas WHERE id = ?`, personaID)
+	return err
+}
+
+// EncodePersonaNames converts a persona selection to the JSON stored in tests.personas and
+// scenarios.personas.
+func EncodePersonaNames(names []string) (string, error) {
+	if len(names) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(names)
+	return string(b), err
+}
+
+// DecodePersonaNames reads a stored persona selection.
+func DecodePersonaNames(stored string) ([]string, error) {
+	names := []string{}
+	if stored == "" {
+		return names, nil
+	}
+	err := json.Unmarshal([]byte(stored), &names)
+	return names, err
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrPromptInUse is returned when deleting a prompt version that a project still selects or
+// that a run was made with.
+var ErrPromptInUse = errors.New("prompt version is selected by a project or recorded by a run")
+
+// PromptRepo stores the prompt registry. A prompt version's template never changes once
+// created, so a run's recorded prompt version always identifies the exact text it used.
+type PromptRepo interface {
+	Create(prompt *Prompt) error
+	GetByID(promptID int) (*Prompt, error)
+	GetVersion(name, version string) (*Prompt, error)
+	List(name, kind string) ([]Prompt, error)
+	UpdateDescription(promptID int, description string) error
+	Delete(promptID int) error
+}
+
+type Prompt struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`    // "simulator" or "judge"
+	Version     string `json:"version"` // Defaults to the next integer version of Name
+	Template    string `json:"template"`
+	Description string `json:"description"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// Ref returns the reference projects and runs use for the prompt version, e.g. "support@3".
+func (p *Prompt) Ref() string {
+	return PromptRef(p.Name, p.Version)
+}
+
+// PromptRef formats a prompt reference.
+func PromptRef(name, version string) string {
+	return name + "@" + version
+}
+
+// ParsePromptRef splits a "name@version" reference.
+func ParsePromptRef(ref string) (name, version string, err error) {
+	i := strings.LastIndex(ref, "@")
+	if i <= 0 || i == len(ref)-1 {
+		return "", "", fmt.Errorf("invalid prompt reference %q, expected name@version", ref)
+	}
+	return ref[:i], ref[i+1:], nil
+}
+
+type PromptRepository struct {
+	db *sql.DB
+}
+
+func NewPromptRepository(db *sql.DB) PromptRepo {
+	return &PromptRepository{db: db}
+}
+
+const promptColumns = "id, name, kind, version, template, description, created_at"
+
+func scanPrompt(row interface{ Scan(dest ...any) error }) (*Prompt, error) {
+	var p Prompt
+	if err := row.Scan(&p.ID, &p.Name, &p.Kind, &p.Version, &p.Template, &p.Description, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Create stores a new prompt version. Without a version, the next integer version of the
+// name is assigned.
+func (r *PromptRepository) Create(p *Prompt) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT kind, version FROM prompts WHERE name = ?`, p.Name)
+	if err != nil {
+		return err
+	}
+	latest := 0
+	for rows.Next() {
+		var kind, version string
+		if err := rows.Scan(&kind, &version); err != nil {
+			rows.Close()
+			return err
+		}
+		if kind != p.Kind {
+			rows.Close()
+			return fmt.Errorf("prompt %q is a %s prompt", p.Name, kind)
+		}
+		if n, err := strconv.Atoi(version); err == nil && n > latest {
+			latest = n
+		}
+	}
+	rows.Close()
+	if p.Version == "" {
+		p.Version = strconv.Itoa(latest + 1)
+	}
+
+	res, err := tx.Exec(`INSERT INTO prompts (name, kind, version, template, description) VALUES (?, ?, ?, ?, ?)`,
+		p.Name, p.Kind, p.Version, p.Template, p.Description)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return fmt.Errorf("prompt %s already exists", p.Ref())
+		}
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	created, err := r.GetByID(int(id))
+	if err != nil {
+		return err
+	}
+	*p = *created
+	return nil
+}
+
+func (r *PromptRepository) GetByID(promptID int) (*Prompt, error) {
+	p, err := scanPrompt(r.db.QueryRow(`SELECT `+promptColumns+` FROM prompts WHERE id = ?`, promptID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+func (r *PromptRepository) GetVersion(name, version string) (*Prompt, error) {
+	p, err := scanPrompt(r.db.QueryRow(`SELECT `+promptColumns+` FROM prompts WHERE name = ? AND version = ?`, name, version))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+// List returns the prompt versions, optionally filtered by name and kind.
+func (r *PromptRepository) List(name, kind string) ([]Prompt, error) {
+	query := `SELECT ` + promptColumns + ` FROM prompts WHERE (? = '' OR name = ?) AND (? = '' OR kind = ?) ORDER BY name, id`
+	rows, err := r.db.Query(query, name, name, kind, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prompts []Prompt
+	for rows.Next() {
+		p, err := scanPrompt(rows)
+		if err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, *p)
+	}
+	return prompts, rows.Err()
+}
+
+func (r *PromptRepository) UpdateDescription(promptID int, description string) error {
+	_, err := r.db.Exec(`UPDATE prompts SET description = ? WHERE id = ?`, description, promptID)
+	return err
+}
+
+// Delete removes a prompt version unless a project selects it or a run recorded it in
+// runs.prompt, so past runs can still be reproduced.
+func (r *PromptRepository) Delete(promptID int) error {
+	p, err := r.GetByID(promptID)
+	if err != nil || p == nil {
+		return err
+	}
+	var uses int
+	ref := p.Ref()
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM tests WHERE simulator_prompt = ? OR judge_prompt = ?`, ref, ref).Scan(&uses); err != nil {
+		return err
+	}
+	if uses > 0 {
+		return ErrPromptInUse
+	}
+	// Older runs may hold a prompt that isn't JSON; those can't reference a version.
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM runs WHERE CASE WHEN json_valid(prompt) THEN json_extract(prompt, '$.simulator') = ? OR json_extract(prompt, '$.judge') = ? END`, ref, ref).Scan(&uses); err != nil {
+		return err
+	}
+	if uses > 0 {
+		return ErrPromptInUse
+	}
+	_, err = r.db.Exec(`DELETE FROM prompts WHERE id = ?`, promptID)
+	return err
+}
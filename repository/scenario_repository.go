@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strconv"
 )
@@ -12,6 +13,9 @@ type Scenario struct {
 	Description    string
 	ExpectedOutput string
 	Status         string
+	Personas       []string            // Persona names the scenario runs with; empty uses the project's
+	Language       string              // Conversation language code; empty uses the persona's or the project's
+	DataTable      []map[string]string // Values of the {{variables}} in Description and ExpectedOutput, one row per case
 }
 
 type ScenarioRepo interface {
@@ -107,38 +111,56 @@ func (r *ScenarioRepository) CreateScenario(testID int, description, expectedOut
 	return scenario, nil
 }
 
+const scenarioColumns = "id, test_id, description, expected_output, status, personas, language, data_table"
+
+func scanScenario(row interface{ Scan(dest ...any) error }) (*Scenario, error) {
+	var s Scenario
+	var personas, dataTable string
+	if err := row.Scan(&s.ID, &s.TestID, &s.Description, &s.ExpectedOutput, &s.Status, &personas, &s.Language, &dataTable); err != nil {
+		return nil, err
+	}
+	var err error
+	if s.Personas, err = DecodePersonaNames(personas); err != nil {
+		return nil, fmt.Errorf("invalid personas for scenario %s: %w", s.ID, err)
+	}
+	if s.DataTable, err = DecodeDataTable(dataTable); err != nil {
+		return nil, fmt.Errorf("invalid data_table for scenario %s: %w", s.ID, err)
+	}
+	return &s, nil
+}
+
 // GetScenariosByTestID fetches all scenarios for a test, ordered by creation.
 func (r *ScenarioRepository) GetScenariosByTestID(testID int) ([]Scenario, error) {
-	rows, err := r.db.Query("SELECT id, test_id, description, expected_output, status FROM scenarios WHERE test_id = ? ORDER BY id ASC", testID)
+	rows, err := r.db.Query("SELECT "+scenarioColumns+" FROM scenarios WHERE test_id = ? ORDER BY id ASC", testID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var scenarios []Scenario
 	for rows.Next() {
-		var s Scenario
-		if err := rows.Scan(&s.ID, &s.TestID, &s.Description, &s.ExpectedOutput, &s.Status); err != nil {
+		s, err := scanScenario(rows)
+		if err != nil {
 			return nil, err
 		}
-		scenarios = append(scenarios, s)
+		scenarios = append(scenarios, *s)
 	}
 	return scenarios, nil
 }
 
 // GetScenarioByID retrieves a scenario by its ID.
 func (r *ScenarioRepository) GetScenarioByID(scenarioID int) (*Scenario, error) {
-	var s Scenario
-	err := r.db.QueryRow("SELECT id, test_id, description, expected_output, status FROM scenarios WHERE id = ?", scenarioID).Scan(&s.ID, &s.TestID, &s.Description, &s.ExpectedOutput, &s.Status)
+	s, err := scanScenario(r.db.QueryRow("SELECT "+scenarioColumns+" FROM scenarios WHERE id = ?", scenarioID))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	return &s, nil
+	return s, nil
 }
 
-// UpdateScenario updates scenario fields. Only description and expected_output are updatable.
+// UpdateScenario updates scenario fields: description, expected_output, status, personas ([]string),
+// language and data_table ([]map[string]string).
 func (r *ScenarioRepository) UpdateScenario(scenarioID int, updates map[string]interface{}) (*Scenario, error) {
 	s, err := r.GetScenarioByID(scenarioID)
 	if err != nil {
@@ -156,16 +178,52 @@ func (r *ScenarioRepository) UpdateScenario(scenarioID int, updates map[string]i
 	if status, ok := updates["status"].(string); ok {
 		s.Status = status
 	}
+	if personas, ok := updates["personas"].([]string); ok {
+		s.Personas = personas
+	}
+	if language, ok := updates["language"].(string); ok {
+		s.Language = language
+	}
+	if table, ok := updates["data_table"].([]map[string]string); ok {
+		s.DataTable = table
+	}
 	if valid, msg := r.ValidateScenarioFormat(s); !valid {
 		return nil, fmt.Errorf("invalid scenario: %s", msg)
 	}
-	_, err = r.db.Exec("UPDATE scenarios SET description = ?, expected_output = ?, status = ? WHERE id = ?", s.Description, s.ExpectedOutput, s.Status, scenarioID)
+	personas, err := EncodePersonaNames(s.Personas)
+	if err != nil {
+		return nil, err
+	}
+	dataTable, err := EncodeDataTable(s.DataTable)
+	if err != nil {
+		return nil, err
+	}
+	_, err = r.db.Exec("UPDATE scenarios SET description = ?, expected_output = ?, status = ?, personas = ?, language = ?, data_table = ? WHERE id = ?", s.Description, s.ExpectedOutput, s.Status, personas, s.Language, dataTable, scenarioID)
 	if err != nil {
 		return nil, err
 	}
 	return s, nil
 }
 
+// EncodeDataTable converts a data table to the JSON stored in scenarios.data_table.
+func EncodeDataTable(table []map[string]string) (string, error) {
+	if len(table) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(table)
+	return string(b), err
+}
+
+// DecodeDataTable reads a stored data table.
+func DecodeDataTable(stored string) ([]map[string]string, error) {
+	table := []map[string]string{}
+	if stored == "" {
+		return table, nil
+	}
+	err := json.Unmarshal([]byte(stored), &table)
+	return table, err
+}
+
 // DeleteScenario removes a scenario by ID.
 func (r *ScenarioRepository) DeleteScenario(scenarioID int) error {
 	// Check existence
@@ -12,6 +12,7 @@ type Store struct {
 	Scenario    ScenarioRepo
 	Test        TestRepo
 	TestRun     TestRunRepo
+	Judgment    JudgmentRepo
 }
 
 func NewStore(db *sql.DB) *Store {
@@ -20,6 +21,7 @@ func NewStore(db *sql.DB) *Store {
 		Scenario:    NewScenarioRepository(db),
 		Test:        NewTestRepository(db),
 		TestRun:     NewTestRunRepository(db),
+		Judgment:    NewJudgmentRepository(db),
 	}
 }
 
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 )
@@ -12,11 +13,63 @@ type Test struct {
 	TenantID        string
 	ProjectID       string
 	MaxInteractions int
-	CreatedAt       string
+	LLMProvider     string          `json:"llm_provider"`            // Simulator provider, e.g. "cohere"
+	LLMModel        string          `json:"llm_model"`               // Simulator model; empty means the provider default
+	TargetType      string          `json:"target_type"`             // Adapter for the bot under test, e.g. "knovvu"
+	TargetConfig    json.RawMessage `json:"target_config,omitempty"` // Adapter settings, e.g. the webhook URL
+	StartEvent      string          `json:"start_event"`             // Triggers the VA greeting, e.g. "conversationUpdate"; empty lets the simulator speak first
+	Personas        []string        `json:"personas"`                // Persona names every scenario runs with; empty plays the generic tester
+	Language        string          `json:"language"`                // Conversation language code, e.g. "tr"; empty leaves it to the prompt
+	PromptSelection
+	KnovvuSettings
+	CreatedAt string
+}
+
+// PromptSelection names the registry prompt versions a project runs with, as "name@version"
+// references. Empty fields use the built-in prompts.
+type PromptSelection struct {
+	SimulatorPrompt string `json:"simulator_prompt"`
+	JudgePrompt     string `json:"judge_prompt"`
+}
+
+// KnovvuSettings configures how a project's VA is reached. Empty fields use the knovvu package defaults.
+type KnovvuSettings struct {
+	KnovvuRegion       string            `json:"knovvu_region"`
+	KnovvuBaseURL      string            `json:"knovvu_base_url"`
+	KnovvuIdentityURL  string            `json:"knovvu_identity_url"`
+	KnovvuChannel      string            `json:"knovvu_channel"`
+	KnovvuResponseType string            `json:"knovvu_response_type"`
+	KnovvuHeaders      map[string]string `json:"knovvu_headers"` // Stored as a JSON object
+}
+
+// TestColumns lists the tests columns read by ScanTest, in order.
+const TestColumns = "id, name, tenant_id, project_id, max_interactions, llm_provider, llm_model, target_type, target_config, start_event, simulator_prompt, judge_prompt, personas, language, knovvu_region, knovvu_base_url, knovvu_identity_url, knovvu_channel, knovvu_response_type, knovvu_headers, created_at"
+
+// ScanTest reads a row selected with TestColumns.
+func ScanTest(row interface{ Scan(dest ...any) error }) (*Test, error) {
+	var t Test
+	var headers, targetConfig, personas string
+	if err := row.Scan(&t.ID, &t.Name, &t.TenantID, &t.ProjectID, &t.MaxInteractions, &t.LLMProvider, &t.LLMModel, &t.TargetType, &targetConfig, &t.StartEvent, &t.SimulatorPrompt, &t.JudgePrompt, &personas, &t.Language,
+		&t.KnovvuRegion, &t.KnovvuBaseURL, &t.KnovvuIdentityURL, &t.KnovvuChannel, &t.KnovvuResponseType, &headers, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	if targetConfig != "" {
+		t.TargetConfig = json.RawMessage(targetConfig)
+	}
+	if headers != "" {
+		if err := json.Unmarshal([]byte(headers), &t.KnovvuHeaders); err != nil {
+			return nil, fmt.Errorf("invalid knovvu_headers for test %d: %w", t.ID, err)
+		}
+	}
+	var err error
+	if t.Personas, err = DecodePersonaNames(personas); err != nil {
+		return nil, fmt.Errorf("invalid personas for test %d: %w", t.ID, err)
+	}
+	return &t, nil
 }
 
 type TestRepo interface {
-	CreateTest(name, tenantID, projectID string, maxInteractions int) (int, error)
+	CreateTest(name, tenantID, projectID string, maxInteractions int, llmProvider, llmModel, targetType string, targetConfig json.RawMessage, startEvent string, prompts PromptSelection, personas []string, language string, knovvu KnovvuSettings) (int, error)
 	GetTestByID(testID int) (*Test, error)
 	GetTestsByTenant(tenantID string, limit, offset int) ([]Test, error)
 	GetTestsByProject(tenantID, projectID string) ([]Test, error)
@@ -35,7 +88,7 @@ func NewTestRepository(db *sql.DB) TestRepo {
 	return &TestRepository{db: db}
 }
 
-func (r *TestRepository) CreateTest(name, tenantID, projectID string, maxInteractions int) (int, error) {
+func (r *TestRepository) CreateTest(name, tenantID, projectID string, maxInteractions int, llmProvider, llmModel, targetType string, targetConfig json.RawMessage, startEvent string, prompts PromptSelection, personas []string, language string, knovvu KnovvuSettings) (int, error) {
 	// Validate tenant-project pair (stub, always true for now)
 	valid, err := r.ValidateTenantProjectPair(tenantID, projectID)
 	if err != nil {
@@ -47,12 +100,28 @@ func (r *TestRepository) CreateTest(name, tenantID, projectID string, maxInterac
 	if maxInteractions <= 0 {
 		maxInteractions = 10
 	}
+	if llmProvider == "" {
+		llmProvider = "cohere"
+	}
+	if targetType == "" {
+		targetType = "knovvu"
+	}
+	headers, err := EncodeKnovvuHeaders(knovvu.KnovvuHeaders)
+	if err != nil {
+		return 0, err
+	}
+	personaNames, err := EncodePersonaNames(personas)
+	if err != nil {
+		return 0, err
+	}
 	tx, err := r.db.Begin()
 	if err != nil {
 		return 0, err
 	}
 	defer tx.Rollback()
-	res, err := tx.Exec(`INSERT INTO tests (name, tenant_id, project_id, max_interactions) VALUES (?, ?, ?, ?)`, name, tenantID, projectID, maxInteractions)
+	res, err := tx.Exec(`INSERT INTO tests (name, tenant_id, project_id, max_interactions, llm_provider, llm_model, target_type, target_config, start_event, simulator_prompt, judge_prompt, personas, language, knovvu_region, knovvu_base_url, knovvu_identity_url, knovvu_channel, knovvu_response_type, knovvu_headers) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		name, tenantID, projectID, maxInteractions, llmProvider, llmModel, targetType, string(targetConfig), startEvent, prompts.SimulatorPrompt, prompts.JudgePrompt, personaNames, language,
+		knovvu.KnovvuRegion, knovvu.KnovvuBaseURL, knovvu.KnovvuIdentityURL, knovvu.KnovvuChannel, knovvu.KnovvuResponseType, headers)
 	if err != nil {
 		return 0, err
 	}
@@ -72,15 +141,15 @@ func (r *TestRepository) ValidateTenantProjectPair(tenantID, projectID string) (
 }
 
 func (r *TestRepository) GetTestByID(testID int) (*Test, error) {
-	row := r.db.QueryRow(`SELECT id, name, tenant_id, project_id, max_interactions, created_at FROM tests WHERE id = ?`, testID)
-	var t Test
-	if err := row.Scan(&t.ID, &t.Name, &t.TenantID, &t.ProjectID, &t.MaxInteractions, &t.CreatedAt); err != nil {
+	row := r.db.QueryRow(`SELECT `+TestColumns+` FROM tests WHERE id = ?`, testID)
+	t, err := ScanTest(row)
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &t, nil
+	return t, nil
 }
 
 func (r *TestRepository) DeleteTest(testID int) error {
@@ -137,3 +206,12 @@ func (r *TestRepository) UpdateTest(testID int, updates map[string]interface{})
 	_, err := r.db.Exec(query, args...)
 	return err
 }
+
+// EncodeKnovvuHeaders converts extra Knovvu headers to the JSON stored in tests.knovvu_headers.
+func EncodeKnovvuHeaders(headers map[string]string) (string, error) {
+	if len(headers) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(headers)
+	return string(b), err
+}
@@ -5,19 +5,36 @@ import (
 )
 
 type TestRun struct {
-	ID               int
-	ScenarioID       int
-	Status           string
-	Verdict          string
-	VerdictReasoning string
-	StartedAt        string
-	CompletedAt      *string
+	ID                       int
+	ScenarioID               int
+	Status                   string
+	Verdict                  string
+	VerdictReasoning         string
+	TesterModel              string  // Simulator provider/model that drove the run, e.g. "cohere/command-a-03-2025"
+	JudgeModel               string  // Judge provider/model that produced the verdict
+	JudgeAgreement           float64 // Share of panel judges agreeing with the verdict; 0 without a panel
+	Confidence               string
+	ScenarioCompletionScore  float64
+	ConversationQualityScore float64
+	Prompt                   string // Prompt versions used by the run, as JSON, e.g. {"simulator":"builtin@2.0","judge":"builtin@2.0"}
+	ExperimentID             int    // Experiment the run belongs to; 0 outside experiments
+	Variant                  string // Experiment variant that produced the run
+	Persona                  string // Persona the simulator played; empty for the generic tester
+	Language                 string // Code of the language the run was conducted in; empty when unset
+	LanguageMismatches       int    // VA replies detected in another language than the run's
+	DataRow                  int    // Data table row the scenario was rendered with; 0 without a data table
+	Variables                string // Values of that row, as JSON
+	StartedAt                string
+	CompletedAt              *string
 }
 
 type TestRunRepo interface {
 	CreateTestRun(scenarioID int, metadata map[string]interface{}) (int, error)
 	GetTestRunByID(testRunID int) (*TestRun, error)
 	UpdateTestRunStatus(testRunID int, status string, verdict *string, verdictReasoning *string) error
+	UpdateJudgeAgreement(testRunID int, agreement float64) error
+	UpdateJudgmentScores(testRunID int, confidence string, completionScore, qualityScore float64) error
+	UpdateLanguageMismatches(testRunID int, mismatches int) error
 	GetTestRunsByScenario(scenarioID int, limit, offset int) ([]TestRun, error)
 	GetRecentTestRuns(limit int, tenantID, projectID *string) ([]TestRun, error)
 	GetTestRunStats(scenarioID int, filter map[string]interface{}) (map[string]interface{}, error)
@@ -47,8 +64,20 @@ func (r *TestRunRepository) CreateTestRun(scenarioID int, metadata map[string]in
 	if val, ok := metadata["status"].(string); ok {
 		status = val
 	}
-	stmt := `INSERT INTO runs (scenario_id, status) VALUES (?, ?)`
-	res, err := r.db.Exec(stmt, scenarioID, status)
+	testerModel, _ := metadata["tester_model"].(string)
+	judgeModel, _ := metadata["judge_model"].(string)
+	prompt, _ := metadata["prompt"].(string)
+	variant, _ := metadata["variant"].(string)
+	persona, _ := metadata["persona"].(string)
+	language, _ := metadata["language"].(string)
+	dataRow, _ := metadata["data_row"].(int)
+	variables, _ := metadata["variables"].(string)
+	var experimentID sql.NullInt64
+	if id, ok := metadata["experiment_id"].(int); ok && id > 0 {
+		experimentID = sql.NullInt64{Int64: int64(id), Valid: true}
+	}
+	stmt := `INSERT INTO runs (scenario_id, status, tester_model, judge_model, prompt, experiment_id, variant, persona, language, data_row, variables) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := r.db.Exec(stmt, scenarioID, status, testerModel, judgeModel, prompt, experimentID, variant, persona, language, dataRow, variables)
 	if err != nil {
 		return 0, err
 	}
@@ -80,11 +109,13 @@ func (r *TestRunRepository) GetTestExecutionSummary(filter map[string]interface{
 }
 
 func (r *TestRunRepository) GetTestRunByID(testRunID int) (*TestRun, error) {
-	stmt := `SELECT id, scenario_id, status, started_at, completed_at FROM runs WHERE id = ?`
+	stmt := `SELECT id, scenario_id, status, COALESCE(verdict, ''), COALESCE(verdict_reasoning, ''), COALESCE(tester_model, ''), COALESCE(judge_model, ''), COALESCE(judge_agreement, 0),
+		COALESCE(confidence, ''), COALESCE(scenario_completion_score, 0), COALESCE(conversation_quality_score, 0), COALESCE(prompt, ''), COALESCE(experiment_id, 0), variant, persona, language, language_mismatches, data_row, variables, started_at, completed_at FROM runs WHERE id = ?`
 	row := r.db.QueryRow(stmt, testRunID)
 	var tr TestRun
 	var completedAt sql.NullString
-	if err := row.Scan(&tr.ID, &tr.ScenarioID, &tr.Status, &tr.StartedAt, &completedAt); err != nil {
+	if err := row.Scan(&tr.ID, &tr.ScenarioID, &tr.Status, &tr.Verdict, &tr.VerdictReasoning, &tr.TesterModel, &tr.JudgeModel, &tr.JudgeAgreement,
+		&tr.Confidence, &tr.ScenarioCompletionScore, &tr.ConversationQualityScore, &tr.Prompt, &tr.ExperimentID, &tr.Variant, &tr.Persona, &tr.Language, &tr.LanguageMismatches, &tr.DataRow, &tr.Variables, &tr.StartedAt, &completedAt); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -104,7 +135,8 @@ func (r *TestRunRepository) GetTestRunStats(scenarioID int, filter map[string]in
 }
 
 func (r *TestRunRepository) GetTestRunsByScenario(scenarioID int, limit, offset int) ([]TestRun, error) {
-	stmt := `SELECT id, scenario_id, status, started_at, completed_at, verdict, verdict_reasoning FROM runs WHERE scenario_id = ? ORDER BY started_at DESC LIMIT ? OFFSET ?`
+	stmt := `SELECT id, scenario_id, status, started_at, completed_at, COALESCE(verdict, ''), COALESCE(verdict_reasoning, ''), COALESCE(tester_model, ''), COALESCE(judge_model, ''), COALESCE(judge_agreement, 0),
+		COALESCE(confidence, ''), COALESCE(scenario_completion_score, 0), COALESCE(conversation_quality_score, 0), COALESCE(prompt, ''), COALESCE(experiment_id, 0), variant, persona, language, language_mismatches, data_row, variables FROM runs WHERE scenario_id = ? ORDER BY started_at DESC LIMIT ? OFFSET ?`
 	rows, err := r.db.Query(stmt, scenarioID, limit, offset)
 	if err != nil {
 		return nil, err
@@ -115,7 +147,8 @@ func (r *TestRunRepository) GetTestRunsByScenario(scenarioID int, limit, offset
 	for rows.Next() {
 		var tr TestRun
 		var completedAt sql.NullString
-		if err := rows.Scan(&tr.ID, &tr.ScenarioID, &tr.Status, &tr.StartedAt, &completedAt, &tr.Verdict, &tr.VerdictReasoning); err != nil {
+		if err := rows.Scan(&tr.ID, &tr.ScenarioID, &tr.Status, &tr.StartedAt, &completedAt, &tr.Verdict, &tr.VerdictReasoning, &tr.TesterModel, &tr.JudgeModel, &tr.JudgeAgreement,
+			&tr.Confidence, &tr.ScenarioCompletionScore, &tr.ConversationQualityScore, &tr.Prompt, &tr.ExperimentID, &tr.Variant, &tr.Persona, &tr.Language, &tr.LanguageMismatches, &tr.DataRow, &tr.Variables); err != nil {
 			return nil, err
 		}
 		if completedAt.Valid {
@@ -168,3 +201,19 @@ func (r *TestRunRepository) UpdateTestRunStatus(testRunID int, status string, ve
 	_, err := r.db.Exec(stmt, status, verdict, verdictReasoning, testRunID)
 	return err
 }
+
+func (r *TestRunRepository) UpdateLanguageMismatches(testRunID int, mismatches int) error {
+	_, err := r.db.Exec(`UPDATE runs SET language_mismatches = ? WHERE id = ?`, mismatches, testRunID)
+	return err
+}
+
+func (r *TestRunRepository) UpdateJudgeAgreement(testRunID int, agreement float64) error {
+	_, err := r.db.Exec(`UPDATE runs SET judge_agreement = ? WHERE id = ?`, agreement, testRunID)
+	return err
+}
+
+func (r *TestRunRepository) UpdateJudgmentScores(testRunID int, confidence string, completionScore, qualityScore float64) error {
+	stmt := `UPDATE runs SET confidence = ?, scenario_completion_score = ?, conversation_quality_score = ? WHERE id = ?`
+	_, err := r.db.Exec(stmt, confidence, completionScore, qualityScore, testRunID)
+	return err
+}
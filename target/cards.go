@@ -0,0 +1,218 @@
+package target
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Attachment content types parsed into cards.
+const (
+	HeroCardType      = "application/vnd.microsoft.card.hero"
+	ThumbnailCardType = "application/vnd.microsoft.card.thumbnail"
+	AdaptiveCardType  = "application/vnd.microsoft.card.adaptive"
+)
+
+// Button action types.
+const (
+	ActionIMBack      = "imBack"
+	ActionPostBack    = "postBack"
+	ActionMessageBack = "messageBack"
+	ActionOpenURL     = "openUrl"
+	ActionSubmit      = "Action.Submit"
+	ActionExecute     = "Action.Execute"
+)
+
+// CarouselLayout is the attachment layout of cards shown side by side.
+const CarouselLayout = "carousel"
+
+// CardAction is a button of a card or a suggested action.
+type CardAction struct {
+	Type        string      `json:"type"`
+	Title       string      `json:"title"`
+	Value       interface{} `json:"value,omitempty"` // imBack/postBack value, messageBack value, Action.Submit data or URL
+	Text        string      `json:"text,omitempty"`  // messageBack text
+	DisplayText string      `json:"display_text,omitempty"`
+}
+
+// Card is a hero, thumbnail or adaptive card reduced to what a user sees and can click.
+type Card struct {
+	ContentType string       `json:"content_type"`
+	Title       string       `json:"title,omitempty"`
+	Subtitle    string       `json:"subtitle,omitempty"`
+	Text        string       `json:"text,omitempty"` // Adaptive card text blocks, one per line
+	Buttons     []CardAction `json:"buttons,omitempty"`
+}
+
+// ValueString returns the action's value as text: strings as is, other values as JSON.
+func (a CardAction) ValueString() string {
+	switch v := a.Value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+// Payload returns the text and value of the message activity a click on the action sends,
+// the way Web Chat does: imBack posts its value as visible text, postBack posts it hidden,
+// messageBack posts its text and value, Action.Submit posts its data as the value. postBack
+// reports whether the activity should be flagged as a postBack in its channel data.
+func (a CardAction) Payload() (text string, value interface{}, postBack bool) {
+	switch a.Type {
+	case ActionIMBack:
+		return a.ValueString(), nil, false
+	case ActionPostBack:
+		if s, ok := a.Value.(string); ok {
+			return s, nil, true
+		}
+		return "", a.Value, true
+	case ActionMessageBack:
+		return a.Text, a.Value, true
+	case ActionSubmit, ActionExecute:
+		return "", a.Value, true
+	}
+	if s := a.ValueString(); s != "" {
+		return s, nil, false
+	}
+	return a.Title, nil, false
+}
+
+// ParseCards converts Bot Framework attachments into cards. Attachments of other content
+// types, such as images, are skipped.
+func ParseCards(attachments []interface{}) []Card {
+	var cards []Card
+	for _, att := range attachments {
+		m, ok := att.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		contentType, _ := m["contentType"].(string)
+		content, _ := m["content"].(map[string]interface{})
+		if content == nil {
+			continue
+		}
+		switch contentType {
+		case HeroCardType, ThumbnailCardType:
+			cards = append(cards, Card{
+				ContentType: contentType,
+				Title:       stringField(content, "title"),
+				Subtitle:    stringField(content, "subtitle"),
+				Text:        stringField(content, "text"),
+				Buttons:     ParseActions(content["buttons"]),
+			})
+		case AdaptiveCardType:
+			card := Card{ContentType: contentType}
+			var lines []string
+			adaptiveElements(content["body"], &lines, &card.Buttons)
+			adaptiveActions(content["actions"], &card.Buttons)
+			card.Text = strings.Join(lines, "\n")
+			cards = append(cards, card)
+		}
+	}
+	return cards
+}
+
+// ParseActions converts a list of Bot Framework card actions, or a suggestedActions object
+// with an "actions" list, into CardActions.
+func ParseActions(v interface{}) []CardAction {
+	if m, ok := v.(map[string]interface{}); ok {
+		v = m["actions"]
+	}
+	list, _ := v.([]interface{})
+	var actions []CardAction
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		actions = append(actions, CardAction{
+			Type:        stringField(m, "type"),
+			Title:       stringField(m, "title"),
+			Value:       m["value"],
+			Text:        stringField(m, "text"),
+			DisplayText: stringField(m, "displayText"),
+		})
+	}
+	return actions
+}
+
+// adaptiveElements collects the text and the actions of adaptive card body elements,
+// descending into containers and columns.
+func adaptiveElements(v interface{}, lines *[]string, actions *[]CardAction) {
+	list, _ := v.([]interface{})
+	for _, item := range list {
+		el, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch stringField(el, "type") {
+		case "TextBlock":
+			if text := stringField(el, "text"); text != "" {
+				*lines = append(*lines, text)
+			}
+		case "RichTextBlock":
+			var parts []string
+			inlines, _ := el["inlines"].([]interface{})
+			for _, in := range inlines {
+				switch in := in.(type) {
+				case string:
+					parts = append(parts, in)
+				case map[string]interface{}:
+					parts = append(parts, stringField(in, "text"))
+				}
+			}
+			if text := strings.Join(parts, ""); text != "" {
+				*lines = append(*lines, text)
+			}
+		case "FactSet":
+			facts, _ := el["facts"].([]interface{})
+			for _, f := range facts {
+				if fact, ok := f.(map[string]interface{}); ok {
+					*lines = append(*lines, fmt.Sprintf("%s: %s", stringField(fact, "title"), stringField(fact, "value")))
+				}
+			}
+		case "Container":
+			adaptiveElements(el["items"], lines, actions)
+		case "ColumnSet":
+			columns, _ := el["columns"].([]interface{})
+			for _, c := range columns {
+				if column, ok := c.(map[string]interface{}); ok {
+					adaptiveElements(column["items"], lines, actions)
+				}
+			}
+		case "ActionSet":
+			adaptiveActions(el["actions"], actions)
+		}
+	}
+}
+
+// adaptiveActions collects the clickable actions of an adaptive card. Submit and execute
+// actions carry their data as value, open-URL actions their URL.
+func adaptiveActions(v interface{}, actions *[]CardAction) {
+	list, _ := v.([]interface{})
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		action := CardAction{Type: stringField(m, "type"), Title: stringField(m, "title")}
+		switch action.Type {
+		case ActionSubmit, ActionExecute:
+			action.Value = m["data"]
+		case "Action.OpenUrl":
+			action.Type, action.Value = ActionOpenURL, m["url"]
+		default:
+			continue
+		}
+		*actions = append(*actions, action)
+	}
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
@@ -0,0 +1,138 @@
+package target
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func decodeJSON(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestParseCardsHero(t *testing.T) {
+	attachments := decodeJSON(t, `[
+		{"contentType": "image/png", "contentUrl": "https://example.com/a.png"},
+		{"contentType": "application/vnd.microsoft.card.hero"},
+		"not an attachment",
+		{"contentType": "application/vnd.microsoft.card.hero", "content": {
+			"title": "Accounts", "subtitle": "Pick one", "text": "Which account?",
+			"buttons": [
+				{"type": "imBack", "title": "Checking", "value": "checking"},
+				{"type": "messageBack", "title": "Savings", "text": "savings", "displayText": "Savings please", "value": {"id": 2}},
+				"not a button"
+			]}},
+		{"contentType": "application/vnd.microsoft.card.thumbnail", "content": {"title": "Branch", "subtitle": 7}}
+	]`).([]interface{})
+
+	// Images, attachments without content and malformed entries are skipped
+	want := []Card{
+		{
+			ContentType: HeroCardType,
+			Title:       "Accounts",
+			Subtitle:    "Pick one",
+			Text:        "Which account?",
+			Buttons: []CardAction{
+				{Type: ActionIMBack, Title: "Checking", Value: "checking"},
+				{Type: ActionMessageBack, Title: "Savings", Text: "savings", DisplayText: "Savings please", Value: map[string]interface{}{"id": float64(2)}},
+			},
+		},
+		{ContentType: ThumbnailCardType, Title: "Branch"}, // non-string fields are ignored
+	}
+	if got := ParseCards(attachments); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCards() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseCardsAdaptive(t *testing.T) {
+	attachments := decodeJSON(t, `[{"contentType": "application/vnd.microsoft.card.adaptive", "content": {
+		"body": [
+			{"type": "TextBlock", "text": "Your balance"},
+			{"type": "TextBlock", "text": ""},
+			{"type": "RichTextBlock", "inlines": ["Total: ", {"type": "TextRun", "text": "100 TL"}]},
+			{"type": "FactSet", "facts": [{"title": "IBAN", "value": "TR00"}, {"title": "Owner", "value": "A. Yılmaz"}]},
+			{"type": "Container", "items": [
+				{"type": "ColumnSet", "columns": [
+					{"items": [{"type": "TextBlock", "text": "Left"}]},
+					{"items": [{"type": "ActionSet", "actions": [{"type": "Action.Submit", "title": "Details", "data": {"action": "details"}}]}]}
+				]}
+			]},
+			{"type": "Image", "url": "https://example.com/logo.png"},
+			{"type": "Input.Text", "id": "amount"}
+		],
+		"actions": [
+			{"type": "Action.OpenUrl", "title": "Website", "url": "https://example.com"},
+			{"type": "Action.ShowCard", "title": "More", "card": {}},
+			{"type": "Action.ToggleVisibility", "title": "Hide"},
+			{"type": "Action.Execute", "title": "Refresh", "verb": "refresh", "data": "refresh"}
+		]}}]`).([]interface{})
+
+	want := []Card{{
+		ContentType: AdaptiveCardType,
+		// Text is read in reading order, descending into containers and columns
+		Text: "Your balance\nTotal: 100 TL\nIBAN: TR00\nOwner: A. Yılmaz\nLeft",
+		// Buttons inside the body come before the card's own actions; actions that only
+		// change the card locally can't be sent to the bot and are dropped
+		Buttons: []CardAction{
+			{Type: ActionSubmit, Title: "Details", Value: map[string]interface{}{"action": "details"}},
+			{Type: ActionOpenURL, Title: "Website", Value: "https://example.com"},
+			{Type: ActionExecute, Title: "Refresh", Value: "refresh"},
+		},
+	}}
+	if got := ParseCards(attachments); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCards() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSuggestedActionsAndCarousel(t *testing.T) {
+	activity := newBotActivity("", "Choose a card", decodeJSON(t, `[
+		{"contentType": "application/vnd.microsoft.card.hero", "content": {"title": "Gold", "buttons": [{"type": "postBack", "title": "Apply", "value": "apply_gold"}]}},
+		{"contentType": "application/vnd.microsoft.card.hero", "content": {"title": "Silver", "buttons": [{"type": "postBack", "title": "Apply", "value": "apply_silver"}]}}
+	]`).([]interface{}), CarouselLayout, decodeJSON(t, `{"to": ["user"], "actions": [{"type": "imBack", "title": "Neither", "value": "neither"}]}`))
+
+	if activity.Type != ActivityMessage || activity.AttachmentLayout != CarouselLayout || len(activity.Cards) != 2 {
+		t.Fatalf("newBotActivity() = %+v", activity)
+	}
+	activity.QuickReplies = []string{"Back"}
+	// Card buttons first, then suggested actions, then quick replies
+	var values []string
+	for _, a := range activity.Actions() {
+		values = append(values, a.ValueString())
+	}
+	if want := []string{"apply_gold", "apply_silver", "neither", "Back"}; !reflect.DeepEqual(values, want) {
+		t.Errorf("Actions() values = %v, want %v", values, want)
+	}
+}
+
+func TestCardActionPayload(t *testing.T) {
+	tests := []struct {
+		action       CardAction
+		wantText     string
+		wantValue    interface{}
+		wantPostBack bool
+	}{
+		// imBack shows its value as the user's message
+		{CardAction{Type: ActionIMBack, Title: "Yes", Value: "yes"}, "yes", nil, false},
+		{CardAction{Type: ActionIMBack, Title: "Two", Value: 2.0}, "2", nil, false},
+		// postBack sends its value without showing it
+		{CardAction{Type: ActionPostBack, Value: "no"}, "no", nil, true},
+		{CardAction{Type: ActionPostBack, Value: map[string]interface{}{"id": "1"}}, "", map[string]interface{}{"id": "1"}, true},
+		{CardAction{Type: ActionMessageBack, Text: "savings", DisplayText: "Savings please", Value: "2"}, "savings", "2", true},
+		{CardAction{Type: ActionSubmit, Title: "Details", Value: "details"}, "", "details", true},
+		{CardAction{Type: ActionExecute, Title: "Refresh"}, "", nil, true},
+		// Unknown types fall back to the value, then to the title
+		{CardAction{Type: "call", Title: "Call", Value: "+90 212"}, "+90 212", nil, false},
+		{CardAction{Type: "call", Title: "Call"}, "Call", nil, false},
+	}
+	for _, tt := range tests {
+		text, value, postBack := tt.action.Payload()
+		if text != tt.wantText || !reflect.DeepEqual(value, tt.wantValue) || postBack != tt.wantPostBack {
+			t.Errorf("%+v.Payload() = %q, %v, %t, want %q, %v, %t", tt.action, text, value, postBack, tt.wantText, tt.wantValue, tt.wantPostBack)
+		}
+	}
+}
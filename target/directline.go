@@ -0,0 +1,328 @@
+package target
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDirectLineURL is the public Bot Framework Direct Line endpoint.
+const DefaultDirectLineURL = "https://directline.botframework.com"
+
+// DirectLineConfig describes a bot exposed through Direct Line 3.0. It is stored as the
+// project's target_config.
+type DirectLineConfig struct {
+	BaseURL string `json:"base_url,omitempty"` // Defaults to DefaultDirectLineURL
+	Secret  string `json:"secret"`             // ${VAR} references are read from the environment
+	UserID  string `json:"user_id,omitempty"`  // Defaults to "evaluator"
+	// PollIntervalMs is the delay between two activity polls. Defaults to 500.
+	PollIntervalMs int `json:"poll_interval_ms,omitempty"`
+	// SettleMs is how long no new activity may arrive before a multi-activity reply is
+	// considered complete. Defaults to 1500.
+	SettleMs int `json:"settle_ms,omitempty"`
+	// ReplyTimeoutSeconds bounds the wait for the bot's reply, typing indicators and
+	// follow-up activities included. Defaults to 20.
+	ReplyTimeoutSeconds int `json:"reply_timeout_seconds,omitempty"`
+}
+
+// Activity is the subset of a Bot Framework activity used by the evaluator.
+type Activity struct {
+	Type        string                 `json:"type"`
+	ID          string                 `json:"id,omitempty"`
+	Timestamp   string                 `json:"timestamp,omitempty"`
+	From        ChannelAccount         `json:"from"`
+	Text        string                 `json:"text,omitempty"`
+	Attachments []interface{}          `json:"attachments,omitempty"`
+	ReplyToID   string                 `json:"replyToId,omitempty"`
+	ChannelData map[string]interface{} `json:"channelData,omitempty"`
+	Value       interface{}            `json:"value,omitempty"`
+	Name        string                 `json:"name,omitempty"` // Name of an event activity
+
+	AttachmentLayout string      `json:"attachmentLayout,omitempty"`
+	SuggestedActions interface{} `json:"suggestedActions,omitempty"`
+}
+
+// ChannelAccount identifies the sender of an activity.
+type ChannelAccount struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// DirectLine is a Target that talks to a bot through the Direct Line 3.0 REST API:
+// it starts a conversation, posts activities and polls the activity set with a watermark.
+type DirectLine struct {
+	cfg        DirectLineConfig
+	secret     string
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	conversations map[string]*directLineConversation
+}
+
+type directLineConversation struct {
+	token     string // Conversation-scoped token returned by Direct Line
+	watermark string
+}
+
+// NewDirectLine validates cfg and creates the target.
+func NewDirectLine(cfg DirectLineConfig) (*DirectLine, error) {
+	secret := os.ExpandEnv(cfg.Secret)
+	if secret == "" {
+		return nil, fmt.Errorf("directline target: secret is required")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultDirectLineURL
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.UserID == "" {
+		cfg.UserID = "evaluator"
+	}
+	if cfg.PollIntervalMs <= 0 {
+		cfg.PollIntervalMs = 500
+	}
+	if cfg.SettleMs <= 0 {
+		cfg.SettleMs = 1500
+	}
+	if cfg.ReplyTimeoutSeconds <= 0 {
+		cfg.ReplyTimeoutSeconds = 20
+	}
+	return &DirectLine{
+		cfg:           cfg,
+		secret:        secret,
+		httpClient:    &http.Client{Timeout: 15 * time.Second},
+		conversations: make(map[string]*directLineConversation),
+	}, nil
+}
+
+// ParseDirectLineConfig decodes a project's target_config.
+func ParseDirectLineConfig(raw []byte) (DirectLineConfig, error) {
+	var cfg DirectLineConfig
+	if len(raw) == 0 {
+		return cfg, fmt.Errorf("directline target: target_config is required")
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("directline target: invalid target_config: %w", err)
+	}
+	return cfg, nil
+}
+
+func (d *DirectLine) StartConversation(ctx context.Context) (string, error) {
+	var resp struct {
+		ConversationID string `json:"conversationId"`
+		Token          string `json:"token"`
+	}
+	if err := d.do(ctx, http.MethodPost, "/v3/directline/conversations", d.secret, nil, &resp); err != nil {
+		return "", fmt.Errorf("failed to start Direct Line conversation: %w", err)
+	}
+	if resp.ConversationID == "" {
+		return "", fmt.Errorf("failed to start Direct Line conversation: no conversationId in response")
+	}
+	token := resp.Token
+	if token == "" {
+		token = d.secret
+	}
+	d.mu.Lock()
+	d.conversations[resp.ConversationID] = &directLineConversation{token: token}
+	d.mu.Unlock()
+	return resp.ConversationID, nil
+}
+
+// SendMessage posts the user's message and collects every bot activity that follows it.
+// A reply is complete once no new activity arrived for SettleMs; typing indicators extend
+// the wait up to the reply timeout.
+func (d *DirectLine) SendMessage(ctx context.Context, conversationID, text string) (*Reply, error) {
+	return d.post(ctx, conversationID, Activity{Type: ActivityMessage, Text: strings.TrimSpace(text)})
+}
+
+// SendAction clicks a button: its payload is posted as a message activity, flagged as a
+// postBack in the channel data for postBack, messageBack and Action.Submit buttons.
+func (d *DirectLine) SendAction(ctx context.Context, conversationID string, action CardAction) (*Reply, error) {
+	text, value, postBack := action.Payload()
+	activity := Activity{Type: ActivityMessage, Text: text, Value: value}
+	if postBack {
+		activity.ChannelData = map[string]interface{}{"postBack": true}
+	}
+	return d.post(ctx, conversationID, activity)
+}
+
+// Greet collects the bot's greeting. Direct Line itself sends the conversationUpdate when
+// the conversation starts; any other event is posted as an event activity first.
+func (d *DirectLine) Greet(ctx context.Context, conversationID, event string) (*Reply, error) {
+	if event != StartConversationUpdate {
+		return d.post(ctx, conversationID, Activity{Type: ActivityEvent, Name: event})
+	}
+	conv, err := d.conversation(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	activities, err := d.collect(ctx, conversationID, conv)
+	if err != nil {
+		return nil, err
+	}
+	return activitiesReply(activities), nil
+}
+
+// post sends a user activity and collects the bot's reply.
+func (d *DirectLine) post(ctx context.Context, conversationID string, activity Activity) (*Reply, error) {
+	conv, err := d.conversation(conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var posted struct {
+		ID string `json:"id"`
+	}
+	activity.From = ChannelAccount{ID: d.cfg.UserID}
+	if err := d.do(ctx, http.MethodPost, d.activitiesPath(conversationID), conv.token, activity, &posted); err != nil {
+		return nil, fmt.Errorf("failed to post activity: %w", err)
+	}
+
+	activities, err := d.collect(ctx, conversationID, conv)
+	if err != nil {
+		return nil, err
+	}
+	return activitiesReply(activities), nil
+}
+
+// EndConversation tells the bot the conversation is over.
+func (d *DirectLine) EndConversation(ctx context.Context, conversationID string) error {
+	conv, err := d.conversation(conversationID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		d.mu.Lock()
+		delete(d.conversations, conversationID)
+		d.mu.Unlock()
+	}()
+	activity := Activity{Type: "endOfConversation", From: ChannelAccount{ID: d.cfg.UserID}}
+	return d.do(ctx, http.MethodPost, d.activitiesPath(conversationID), conv.token, activity, nil)
+}
+
+// collect polls the conversation until the bot's reply has settled and returns every bot
+// activity of the turn, typing indicators included. The reply timeout ends the turn even if
+// the bot is still sending activities: a bot that sent no message by then gets an empty
+// reply, which is recorded like any unanswered turn, and one that keeps typing after its
+// message gets what has arrived.
+func (d *DirectLine) collect(ctx context.Context, conversationID string, conv *directLineConversation) ([]Activity, error) {
+	poll := time.Duration(d.cfg.PollIntervalMs) * time.Millisecond
+	settle := time.Duration(d.cfg.SettleMs) * time.Millisecond
+	deadline := time.Now().Add(time.Duration(d.cfg.ReplyTimeoutSeconds) * time.Second)
+
+	var activities []Activity
+	var replied bool
+	var lastActivity time.Time
+	for {
+		var set struct {
+			Activities []Activity `json:"activities"`
+			Watermark  string     `json:"watermark"`
+		}
+		path := d.activitiesPath(conversationID)
+		if conv.watermark != "" {
+			path += "?watermark=" + url.QueryEscape(conv.watermark)
+		}
+		if err := d.do(ctx, http.MethodGet, path, conv.token, nil, &set); err != nil {
+			return nil, fmt.Errorf("failed to get activities: %w", err)
+		}
+		if set.Watermark != "" {
+			conv.watermark = set.Watermark
+		}
+		for _, a := range set.Activities {
+			if a.From.ID == d.cfg.UserID {
+				continue
+			}
+			lastActivity = time.Now()
+			activities = append(activities, a)
+			if a.Type == ActivityMessage {
+				replied = true
+			}
+		}
+
+		now := time.Now()
+		if replied && now.Sub(lastActivity) >= settle {
+			return activities, nil
+		}
+		if now.After(deadline) {
+			return activities, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(poll):
+		}
+	}
+}
+
+// activitiesReply converts the bot activities of one turn into a reply.
+func activitiesReply(activities []Activity) *Reply {
+	reply := &Reply{}
+	for _, a := range activities {
+		reply.Activities = append(reply.Activities, newBotActivity(a.Type, a.Text, a.Attachments, a.AttachmentLayout, a.SuggestedActions))
+	}
+	reply.Raw, _ = json.Marshal(activities)
+	return reply
+}
+
+func (d *DirectLine) conversation(conversationID string) (*directLineConversation, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	conv, ok := d.conversations[conversationID]
+	if !ok {
+		return nil, fmt.Errorf("unknown conversation: %s", conversationID)
+	}
+	return conv, nil
+}
+
+func (d *DirectLine) activitiesPath(conversationID string) string {
+	return "/v3/directline/conversations/" + url.PathEscape(conversationID) + "/activities"
+}
+
+// do sends a Direct Line request and decodes the JSON response into out, if non-nil.
+func (d *DirectLine) do(ctx context.Context, method, path, token string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, d.cfg.BaseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("received non-2xx response: %d\nResponse body: %s", resp.StatusCode, string(raw))
+	}
+	if out == nil || len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w\nResponse body: %s", err, string(raw))
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package target
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDirectLine is a Direct Line service whose bot answers each user message with a script
+// of activities, each shown after its delay. Polls return at most pageSize activities, so
+// replies span several pages.
+type fakeDirectLine struct {
+	*httptest.Server
+	pageSize int
+	script   func(text string) []scheduled
+	typing   bool // add a typing indicator on every poll, like a bot that never stops typing
+
+	mu         sync.Mutex
+	activities []scheduled
+	watermarks []string // watermark of every poll, "" for none
+}
+
+type scheduled struct {
+	Activity
+	at time.Time
+}
+
+func after(d time.Duration, a Activity) scheduled {
+	a.From = ChannelAccount{ID: "bot"}
+	return scheduled{Activity: a, at: time.Now().Add(d)}
+}
+
+func newFakeDirectLine(t *testing.T, script func(text string) []scheduled) *fakeDirectLine {
+	f := &fakeDirectLine{pageSize: 2, script: script}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeDirectLine) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.URL.Path == "/v3/directline/conversations" {
+		if r.Header.Get("Authorization") != "Bearer dl-secret" {
+			http.Error(w, "bad secret", http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"conversationId": "conv/1", "token": "conv-token"})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer conv-token" {
+		http.Error(w, "bad token", http.StatusForbidden)
+		return
+	}
+	if r.Method == http.MethodPost {
+		var a Activity
+		json.NewDecoder(r.Body).Decode(&a)
+		// Direct Line echoes the user's own activity into the activity set
+		f.activities = append(f.activities, scheduled{Activity: a, at: time.Now()})
+		if a.Type == ActivityMessage {
+			f.activities = append(f.activities, f.script(a.Text)...)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id": strconv.Itoa(len(f.activities))})
+		return
+	}
+
+	watermark := r.URL.Query().Get("watermark")
+	f.watermarks = append(f.watermarks, watermark)
+	if f.typing {
+		f.activities = append(f.activities, after(0, Activity{Type: ActivityTyping}))
+	}
+	from, _ := strconv.Atoi(watermark)
+	var page []Activity
+	next := from
+	for next < len(f.activities) && len(page) < f.pageSize && !time.Now().Before(f.activities[next].at) {
+		page = append(page, f.activities[next].Activity)
+		next++
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"activities": page, "watermark": strconv.Itoa(next)})
+}
+
+func newTestDirectLine(t *testing.T, f *fakeDirectLine, settleMs, timeoutSeconds int) (*DirectLine, string) {
+	t.Setenv("TEST_DL_SECRET", "dl-secret")
+	d, err := NewDirectLine(DirectLineConfig{BaseURL: f.URL + "/", Secret: "${TEST_DL_SECRET}", PollIntervalMs: 20, SettleMs: settleMs, ReplyTimeoutSeconds: timeoutSeconds})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conversationID, err := d.StartConversation(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d, conversationID
+}
+
+func replyTexts(reply *Reply) []string {
+	var texts []string
+	for _, a := range reply.Activities {
+		texts = append(texts, a.Type+":"+a.Text)
+	}
+	return texts
+}
+
+func TestDirectLineWatermarkPaging(t *testing.T) {
+	f := newFakeDirectLine(t, func(text string) []scheduled {
+		return []scheduled{
+			after(0, Activity{Type: ActivityTyping}),
+			after(0, Activity{Type: ActivityMessage, Text: "re: " + text}),
+			after(0, Activity{Type: ActivityMessage, Text: "anything else?"}),
+		}
+	})
+	d, conversationID := newTestDirectLine(t, f, 100, 5)
+	ctx := context.Background()
+
+	// The user's echo and three bot activities take two pages; the echo is left out
+	reply, err := d.SendMessage(ctx, conversationID, " hi ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(replyTexts(reply), "|"), "typing:|message:re: hi|message:anything else?"; got != want {
+		t.Errorf("first reply = %s, want %s", got, want)
+	}
+	// The next turn starts from the watermark, so earlier activities aren't repeated
+	reply, err = d.SendMessage(ctx, conversationID, "bye")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(replyTexts(reply), "|"), "typing:|message:re: bye|message:anything else?"; got != want {
+		t.Errorf("second reply = %s, want %s", got, want)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.watermarks[0] != "" || f.watermarks[1] != "2" {
+		t.Errorf("watermarks = %v, want none on the first poll, then the returned one", f.watermarks)
+	}
+	for i := 1; i < len(f.watermarks); i++ {
+		a, _ := strconv.Atoi(f.watermarks[i-1])
+		b, _ := strconv.Atoi(f.watermarks[i])
+		if b < a {
+			t.Errorf("watermark went back from %d to %d", a, b)
+		}
+	}
+}
+
+func TestDirectLineSettleWindow(t *testing.T) {
+	f := newFakeDirectLine(t, func(text string) []scheduled {
+		return []scheduled{
+			after(50*time.Millisecond, Activity{Type: ActivityMessage, Text: "one"}),
+			// Within the settle window of "one", so part of the same reply
+			after(150*time.Millisecond, Activity{Type: ActivityMessage, Text: "two"}),
+			// Long after the reply settled; it's picked up by the next turn
+			after(900*time.Millisecond, Activity{Type: ActivityMessage, Text: "late"}),
+		}
+	})
+	d, conversationID := newTestDirectLine(t, f, 250, 5)
+	ctx := context.Background()
+
+	reply, err := d.SendMessage(ctx, conversationID, "hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(replyTexts(reply), "|"); got != "message:one|message:two" {
+		t.Errorf("reply = %s, want one and two", got)
+	}
+	time.Sleep(900 * time.Millisecond)
+	f.mu.Lock()
+	f.script = func(string) []scheduled { return nil }
+	f.mu.Unlock()
+	reply, err = d.SendMessage(ctx, conversationID, "still there?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(replyTexts(reply), "|"); got != "message:late" {
+		t.Errorf("next reply = %s, want the late message", got)
+	}
+}
+
+func TestDirectLineReplyTimeout(t *testing.T) {
+	// A bot that only ever types gets an empty reply at the timeout
+	f := newFakeDirectLine(t, func(string) []scheduled { return nil })
+	f.typing = true
+	d, conversationID := newTestDirectLine(t, f, 100, 1)
+	start := time.Now()
+	reply, err := d.SendMessage(context.Background(), conversationID, "hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 3*time.Second {
+		t.Errorf("SendMessage() took %v, want about the 1s reply timeout", elapsed)
+	}
+	for _, a := range reply.Activities {
+		if a.Type == ActivityMessage {
+			t.Errorf("reply = %+v, want no message", reply.Activities)
+		}
+	}
+
+	// Typing indicators after the message keep refreshing the settle window; the timeout
+	// still ends the turn with the message
+	f = newFakeDirectLine(t, func(string) []scheduled {
+		return []scheduled{after(0, Activity{Type: ActivityMessage, Text: "working on it"})}
+	})
+	f.typing = true
+	d, conversationID = newTestDirectLine(t, f, 100, 1)
+	bounded, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+	reply, err = d.SendMessage(bounded, conversationID, "hi")
+	if err != nil {
+		t.Fatalf("SendMessage() error = %v, want the turn to end at the reply timeout", err)
+	}
+	if texts := replyTexts(reply); len(texts) < 2 || !contains(texts, "message:working on it") {
+		t.Errorf("reply = %v, want the message and typing indicators", texts)
+	}
+
+	// Cancelling the run stops the wait
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := d.SendMessage(ctx, conversationID, "hi"); err == nil {
+		t.Errorf("SendMessage() ignored the cancelled context")
+	}
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package target
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// extractPath evaluates a small JSONPath subset against a decoded JSON document:
+// "$.a.b", "$.items[0].text", "$.buttons[*].title" and "$" itself. It returns every
+// matched value; a path that doesn't match returns no values and no error.
+func extractPath(doc interface{}, path string) ([]interface{}, error) {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path %q must start with $", path)
+	}
+	steps, err := parsePath(path[1:])
+	if err != nil {
+		return nil, fmt.Errorf("path %q: %w", path, err)
+	}
+
+	current := []interface{}{doc}
+	for _, step := range steps {
+		var next []interface{}
+		for _, v := range current {
+			switch {
+			case step.key != "":
+				if m, ok := v.(map[string]interface{}); ok {
+					if child, ok := m[step.key]; ok {
+						next = append(next, child)
+					}
+				}
+			case step.wildcard:
+				switch c := v.(type) {
+				case []interface{}:
+					next = append(next, c...)
+				case map[string]interface{}:
+					for _, child := range c {
+						next = append(next, child)
+					}
+				}
+			default:
+				if a, ok := v.([]interface{}); ok {
+					i := step.index
+					if i < 0 {
+						i += len(a)
+					}
+					if i >= 0 && i < len(a) {
+						next = append(next, a[i])
+					}
+				}
+			}
+		}
+		current = next
+	}
+	return current, nil
+}
+
+type pathStep struct {
+	key      string
+	index    int
+	wildcard bool
+}
+
+func parsePath(p string) ([]pathStep, error) {
+	var steps []pathStep
+	for len(p) > 0 {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			key := p[:end]
+			if key == "" {
+				return nil, fmt.Errorf("empty key")
+			}
+			if key == "*" {
+				steps = append(steps, pathStep{wildcard: true})
+			} else {
+				steps = append(steps, pathStep{key: key})
+			}
+			p = p[end:]
+		case '[':
+			// Quoted keys end at their closing quote, so they may contain '.' and ']'
+			if inner := strings.TrimLeft(p[1:], " "); len(inner) > 0 && (inner[0] == '\'' || inner[0] == '"') {
+				end := strings.IndexByte(inner[1:], inner[0])
+				if end < 0 {
+					return nil, fmt.Errorf("unterminated quote")
+				}
+				rest := strings.TrimLeft(inner[end+2:], " ")
+				if !strings.HasPrefix(rest, "]") {
+					return nil, fmt.Errorf("expected ] after %s", inner[:end+2])
+				}
+				steps = append(steps, pathStep{key: inner[1 : end+1]})
+				p = rest[1:]
+				continue
+			}
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [")
+			}
+			inner := strings.TrimSpace(p[1:end])
+			switch {
+			case inner == "*":
+				steps = append(steps, pathStep{wildcard: true})
+			default:
+				i, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q", inner)
+				}
+				steps = append(steps, pathStep{index: i})
+			}
+			p = p[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q", p[0])
+		}
+	}
+	return steps, nil
+}
@@ -0,0 +1,98 @@
+package target
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const pathDoc = `{
+	"text": "hello",
+	"messages": [{"text": "first"}, {"text": "second"}, {"type": "typing"}],
+	"meta": {"reply.text": "dotted", "a]b": "bracket", "count": 2, "ok": true, "none": null},
+	"slots": {"city": "Ankara", "date": "today"}
+}`
+
+func decodePathDoc(t *testing.T) interface{} {
+	t.Helper()
+	var doc interface{}
+	if err := json.Unmarshal([]byte(pathDoc), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestExtractPath(t *testing.T) {
+	doc := decodePathDoc(t)
+	tests := []struct {
+		path string
+		want []interface{}
+	}{
+		{"$.messages[*].text", []interface{}{"first", "second"}}, // elements without the key are skipped
+		{"$.messages[-1].type", []interface{}{"typing"}},
+		{"$.messages[-4]", nil}, // negative indexes past the start don't wrap twice
+		{"$.messages[3]", nil},
+		{"$.meta['reply.text']", []interface{}{"dotted"}},
+		{`$.meta[ "reply.text" ]`, []interface{}{"dotted"}},
+		{"$.meta['a]b']", []interface{}{"bracket"}},
+		{"$.meta.count", []interface{}{float64(2)}},
+		{"$.meta.ok", []interface{}{true}},
+		{"$.meta.none", []interface{}{nil}}, // present but null is a match; stringValues drops it
+		{"$.text[0]", nil},                  // indexing a string
+		{"$.text.length", nil},
+		{"$.messages.text", nil}, // keys don't reach into arrays without [*]
+	}
+	for _, tt := range tests {
+		got, err := extractPath(doc, tt.path)
+		if err != nil {
+			t.Errorf("extractPath(%q) error = %v", tt.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("extractPath(%q) = %#v, want %#v", tt.path, got, tt.want)
+		}
+	}
+
+	// A wildcard over an object matches every value, in no particular order
+	got, err := extractPath(doc, "$.slots.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := stringValues(got)
+	sort.Strings(values)
+	if !reflect.DeepEqual(values, []string{"Ankara", "today"}) {
+		t.Errorf("extractPath($.slots.*) = %v", values)
+	}
+
+	if got, err := extractPath(doc, "  $ "); err != nil || !reflect.DeepEqual(got, []interface{}{doc}) {
+		t.Errorf("extractPath($) = %v, %v, want the whole document", got, err)
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	for _, path := range []string{
+		"text",         // no root
+		"$..text",      // recursive descent isn't supported
+		"$.",           // empty key
+		"$.items[0",    // unterminated
+		"$.items[one]", // not an index
+		"$.items['a]",  // unterminated quote
+		"$.items['a'b]",
+		"$text",
+	} {
+		if _, err := extractPath(nil, path); err == nil {
+			t.Errorf("extractPath(%q) succeeded, want an error", path)
+		}
+	}
+
+	// Quoted keys may contain the characters that separate steps
+	steps, err := parsePath("['a.b'][ \"c[0]\" ][2]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []pathStep{{key: "a.b"}, {key: "c[0]"}, {index: 2}}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("parsePath() = %+v, want %+v", steps, want)
+	}
+}
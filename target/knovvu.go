@@ -0,0 +1,78 @@
+package target
+
+import (
+	"context"
+	"evaluator/knovvu"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Knovvu adapts a Knovvu VA to the Target interface.
+type Knovvu struct {
+	client  *knovvu.Client
+	project string // Sent as the Project header
+}
+
+// NewKnovvu creates a target for the given Knovvu project.
+func NewKnovvu(client *knovvu.Client, project string) *Knovvu {
+	return &Knovvu{client: client, project: project}
+}
+
+// StartConversation checks the credentials and returns a new conversation ID. Knovvu
+// creates the conversation with its first message.
+func (k *Knovvu) StartConversation(ctx context.Context) (string, error) {
+	if _, err := k.client.GetToken(ctx); err != nil {
+		return "", fmt.Errorf("failed to get Knovvu token: %w", err)
+	}
+	return uuid.New().String(), nil
+}
+
+func (k *Knovvu) SendMessage(ctx context.Context, conversationID, text string) (*Reply, error) {
+	body, activities, err := k.client.SendMessage(ctx, k.project, text, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return knovvuReply(body, activities), nil
+}
+
+// SendAction clicks a button: its payload is sent as a message activity flagged as a postBack.
+func (k *Knovvu) SendAction(ctx context.Context, conversationID string, action CardAction) (*Reply, error) {
+	text, value, postBack := action.Payload()
+	activity := knovvu.KnovvuRequest{Type: "message", Text: text, Value: value}
+	if postBack {
+		activity.ChannelData = map[string]any{"postBack": true}
+	}
+	body, activities, err := k.client.SendActivity(ctx, k.project, conversationID, activity)
+	if err != nil {
+		return nil, err
+	}
+	return knovvuReply(body, activities), nil
+}
+
+// Greet sends a conversationUpdate announcing the user, or an event activity named event,
+// and returns the VA's greeting.
+func (k *Knovvu) Greet(ctx context.Context, conversationID, event string) (*Reply, error) {
+	activity := knovvu.KnovvuRequest{Type: "event", Name: event}
+	if event == StartConversationUpdate {
+		activity = knovvu.KnovvuRequest{Type: StartConversationUpdate, MembersAdded: []map[string]any{{"id": conversationID}}}
+	}
+	body, activities, err := k.client.SendActivity(ctx, k.project, conversationID, activity)
+	if err != nil {
+		return nil, err
+	}
+	return knovvuReply(body, activities), nil
+}
+
+func knovvuReply(body []byte, activities []knovvu.KnovvuResponse) *Reply {
+	reply := &Reply{Raw: body}
+	for _, a := range activities {
+		reply.Activities = append(reply.Activities, newBotActivity(a.Type, a.Text, a.Attachments, a.AttachmentLayout, a.SuggestedActions))
+	}
+	return reply
+}
+
+// EndConversation is a no-op: Knovvu conversations expire on their own.
+func (k *Knovvu) EndConversation(ctx context.Context, conversationID string) error {
+	return nil
+}
@@ -0,0 +1,184 @@
+package target
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// MockConfig is a scripted dialog flow for the mock target. Rules are tried in order and
+// the first one whose pattern matches the user message (and whose state matches the
+// conversation's state) answers. It is stored as the project's target_config.
+type MockConfig struct {
+	Rules    []MockRule `json:"rules,omitempty"`
+	Fallback string     `json:"fallback,omitempty"` // Reply when no rule matches; {{message}} is replaced
+	Greeting *MockRule  `json:"greeting,omitempty"` // Sent on the start event; Pattern and State are ignored
+}
+
+// MockRule answers user messages matching Pattern.
+type MockRule struct {
+	Pattern      string   `json:"pattern"`         // Regular expression, e.g. "(?i)balance"
+	State        string   `json:"state,omitempty"` // Only match in this state; empty matches any state
+	Reply        string   `json:"reply"`           // {{message}} is replaced with the user message
+	QuickReplies []string `json:"quick_replies,omitempty"`
+	NextState    string   `json:"next_state,omitempty"` // State after this rule; empty keeps the current one
+}
+
+// DefaultMockConfig is a small demo assistant used when a mock target has no configuration.
+var DefaultMockConfig = MockConfig{
+	Rules: []MockRule{
+		{Pattern: `(?i)\b(hi|hello|hey)\b`, Reply: "Hello! I'm the demo assistant. How can I help you today?", QuickReplies: []string{"Check my balance", "Talk to an agent"}},
+		{Pattern: `(?i)balance`, Reply: "Your balance is $1,250.00. Is there anything else I can help with?"},
+		{Pattern: `(?i)\b(agent|human|person)\b`, Reply: "I'm transferring you to an agent. Please hold on."},
+		{Pattern: `(?i)\b(thanks|thank you|bye)\b`, Reply: "You're welcome. Goodbye!"},
+	},
+	Fallback: `Sorry, I didn't understand "{{message}}". You can ask about your balance or talk to an agent.`,
+	Greeting: &MockRule{Reply: "Welcome to the demo bank! What would you like to do?", QuickReplies: []string{"Check my balance", "Talk to an agent"}},
+}
+
+// Mock is an offline Target that answers from a MockConfig.
+type Mock struct {
+	dialog *mockDialog
+}
+
+// NewMock compiles cfg and creates the target. An empty cfg uses DefaultMockConfig.
+func NewMock(cfg MockConfig) (*Mock, error) {
+	dialog, err := newMockDialog(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Mock{dialog: dialog}, nil
+}
+
+// ParseMockConfig decodes a project's target_config. An empty config selects DefaultMockConfig.
+func ParseMockConfig(raw []byte) (MockConfig, error) {
+	var cfg MockConfig
+	if len(raw) == 0 {
+		return cfg, nil
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("mock target: invalid target_config: %w", err)
+	}
+	return cfg, nil
+}
+
+func (m *Mock) StartConversation(ctx context.Context) (string, error) {
+	return uuid.New().String(), nil
+}
+
+func (m *Mock) SendMessage(ctx context.Context, conversationID, text string) (*Reply, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.dialog.respond(conversationID, text), nil
+}
+
+// Greet answers any start event with the configured greeting.
+func (m *Mock) Greet(ctx context.Context, conversationID, event string) (*Reply, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.dialog.greet(conversationID), nil
+}
+
+func (m *Mock) EndConversation(ctx context.Context, conversationID string) error {
+	m.dialog.end(conversationID)
+	return nil
+}
+
+// NewMockHandler serves the dialog over HTTP using the webhook target's default protocol:
+// it accepts {"message": ..., "conversation_id": ...} and answers {"text": ..., "quick_replies": [...]}.
+// Use it with httptest.NewServer to exercise the webhook target without a real bot.
+func NewMockHandler(cfg MockConfig) (http.Handler, error) {
+	dialog, err := newMockDialog(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Message        string `json:"message"`
+			ConversationID string `json:"conversation_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		reply := dialog.respond(req.ConversationID, req.Message).Activities[0]
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"text": reply.Text, "quick_replies": reply.QuickReplies})
+	}), nil
+}
+
+type mockRule struct {
+	MockRule
+	re *regexp.Regexp
+}
+
+// mockDialog evaluates the rules and tracks the state of each conversation.
+type mockDialog struct {
+	rules    []mockRule
+	fallback string
+	greeting *MockRule
+
+	mu     sync.Mutex
+	states map[string]string
+}
+
+func newMockDialog(cfg MockConfig) (*mockDialog, error) {
+	if len(cfg.Rules) == 0 && cfg.Fallback == "" {
+		cfg = DefaultMockConfig
+	}
+	d := &mockDialog{fallback: cfg.Fallback, greeting: cfg.Greeting, states: make(map[string]string)}
+	for i, r := range cfg.Rules {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("mock target: rule %d: %w", i+1, err)
+		}
+		d.rules = append(d.rules, mockRule{MockRule: r, re: re})
+	}
+	return d, nil
+}
+
+func (d *mockDialog) respond(conversationID, text string) *Reply {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	state := d.states[conversationID]
+	for _, r := range d.rules {
+		if r.State != "" && r.State != state {
+			continue
+		}
+		if !r.re.MatchString(text) {
+			continue
+		}
+		if r.NextState != "" {
+			d.states[conversationID] = r.NextState
+		}
+		return TextReply(strings.ReplaceAll(r.Reply, "{{message}}", text), r.QuickReplies)
+	}
+	return TextReply(strings.ReplaceAll(d.fallback, "{{message}}", text), nil)
+}
+
+// greet returns the greeting, or an empty reply when none is configured.
+func (d *mockDialog) greet(conversationID string) *Reply {
+	if d.greeting == nil {
+		return &Reply{}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.greeting.NextState != "" {
+		d.states[conversationID] = d.greeting.NextState
+	}
+	return TextReply(d.greeting.Reply, d.greeting.QuickReplies)
+}
+
+func (d *mockDialog) end(conversationID string) {
+	d.mu.Lock()
+	delete(d.states, conversationID)
+	d.mu.Unlock()
+}
@@ -0,0 +1,107 @@
+package target
+
+import (
+	"context"
+	"encoding/json"
+	"evaluator/llm"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// OpenAIConfig describes an assistant served by an OpenAI-compatible /chat/completions
+// endpoint. It is stored as the project's target_config.
+type OpenAIConfig struct {
+	BaseURL      string  `json:"base_url,omitempty"` // e.g. "http://localhost:8000/v1"; empty means OpenAI
+	Model        string  `json:"model"`
+	SystemPrompt string  `json:"system_prompt,omitempty"`
+	APIKey       string  `json:"api_key,omitempty"` // ${VAR} references are read from the environment
+	Temperature  float64 `json:"temperature,omitempty"`
+	MaxTokens    int     `json:"max_tokens,omitempty"`
+}
+
+// OpenAI is a Target backed by a chat completions endpoint. The target keeps the message
+// history of each conversation, as the API itself is stateless.
+type OpenAI struct {
+	cfg    OpenAIConfig
+	client *llm.OpenAIClient
+
+	mu            sync.Mutex
+	conversations map[string][]llm.ChatMessage
+}
+
+// NewOpenAI validates cfg and creates the target. Without a base URL or API key the
+// OPENAI_API_KEY environment variable is used.
+func NewOpenAI(cfg OpenAIConfig) (*OpenAI, error) {
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("openai target: model is required")
+	}
+	apiKey := os.ExpandEnv(cfg.APIKey)
+	if apiKey == "" && cfg.BaseURL == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	return &OpenAI{
+		cfg:           cfg,
+		client:        llm.NewOpenAICompatibleClient(cfg.BaseURL, apiKey, cfg.Model),
+		conversations: make(map[string][]llm.ChatMessage),
+	}, nil
+}
+
+// ParseOpenAIConfig decodes a project's target_config.
+func ParseOpenAIConfig(raw []byte) (OpenAIConfig, error) {
+	var cfg OpenAIConfig
+	if len(raw) == 0 {
+		return cfg, fmt.Errorf("openai target: target_config is required")
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("openai target: invalid target_config: %w", err)
+	}
+	return cfg, nil
+}
+
+func (o *OpenAI) StartConversation(ctx context.Context) (string, error) {
+	id := uuid.New().String()
+	var history []llm.ChatMessage
+	if o.cfg.SystemPrompt != "" {
+		history = append(history, llm.ChatMessage{Role: "system", Content: o.cfg.SystemPrompt})
+	}
+	o.mu.Lock()
+	o.conversations[id] = history
+	o.mu.Unlock()
+	return id, nil
+}
+
+func (o *OpenAI) SendMessage(ctx context.Context, conversationID, text string) (*Reply, error) {
+	o.mu.Lock()
+	history, ok := o.conversations[conversationID]
+	o.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown conversation: %s", conversationID)
+	}
+
+	// Copy so a failed call leaves the stored history untouched
+	messages := append(append([]llm.ChatMessage{}, history...), llm.ChatMessage{Role: "user", Content: text})
+	content, err := o.client.ChatCompletion(ctx, llm.ChatCompletionRequest{
+		Model:       o.cfg.Model,
+		Messages:    messages,
+		Temperature: o.cfg.Temperature,
+		MaxTokens:   o.cfg.MaxTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	o.conversations[conversationID] = append(messages, llm.ChatMessage{Role: "assistant", Content: content})
+	o.mu.Unlock()
+	return TextReply(content, nil), nil
+}
+
+func (o *OpenAI) EndConversation(ctx context.Context, conversationID string) error {
+	o.mu.Lock()
+	delete(o.conversations, conversationID)
+	o.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,163 @@
+package target
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// TargetType selects the adapter used to talk to the bot under test.
+type TargetType string
+
+const (
+	KnovvuTarget     TargetType = "knovvu"
+	WebhookTarget    TargetType = "webhook"
+	OpenAITarget     TargetType = "openai" // Any OpenAI-compatible chat completions endpoint
+	DirectLineTarget TargetType = "directline"
+	MockTarget       TargetType = "mock" // Scripted offline bot
+)
+
+// DefaultTargetType is used by projects that don't choose a target.
+const DefaultTargetType = KnovvuTarget
+
+// ParseTargetType validates a target type name. An empty name selects the default.
+func ParseTargetType(name string) (TargetType, error) {
+	switch t := TargetType(strings.ToLower(strings.TrimSpace(name))); t {
+	case "":
+		return DefaultTargetType, nil
+	case KnovvuTarget, WebhookTarget, OpenAITarget, DirectLineTarget, MockTarget:
+		return t, nil
+	}
+	return "", fmt.Errorf("unsupported target type: %s", name)
+}
+
+// Target is a conversational system under test. The agent starts a conversation, exchanges
+// messages through it and ends it when the scenario is over.
+type Target interface {
+	// StartConversation opens a new conversation and returns its ID.
+	StartConversation(ctx context.Context) (string, error)
+	// SendMessage sends a user message and returns the bot's reply.
+	SendMessage(ctx context.Context, conversationID, text string) (*Reply, error)
+	// EndConversation releases the conversation. Targets without such a notion return nil.
+	EndConversation(ctx context.Context, conversationID string) error
+}
+
+// StartConversationUpdate is the start event that tells the bot a user joined the
+// conversation, the way a channel does when a chat window opens.
+const StartConversationUpdate = "conversationUpdate"
+
+// Greeter is implemented by targets that can make the bot speak first. event is
+// StartConversationUpdate or the name of an event activity, e.g. "webchat/join".
+type Greeter interface {
+	Greet(ctx context.Context, conversationID, event string) (*Reply, error)
+}
+
+// ActionSender is implemented by targets that can click a button: the action is sent as the
+// postBack, messageBack or Action.Submit activity a real channel would send. Other targets
+// receive the button's value as a plain message.
+type ActionSender interface {
+	SendAction(ctx context.Context, conversationID string, action CardAction) (*Reply, error)
+}
+
+// Activity types of a reply. Targets pass other Bot Framework types through unchanged.
+const (
+	ActivityMessage = "message"
+	ActivityTyping  = "typing"
+	ActivityEvent   = "event"
+)
+
+// ReplyActivity is one activity of a bot's reply: a text, a card, a typing indicator...
+type ReplyActivity struct {
+	Type         string        `json:"type"`
+	Text         string        `json:"text,omitempty"`
+	QuickReplies []string      `json:"quick_replies,omitempty"` // Choices offered to the user, if the target reports them separately
+	Attachments  []interface{} `json:"attachments,omitempty"`   // Bot Framework style attachments, e.g. hero cards
+
+	// Structured form of Attachments and of the activity's suggestedActions
+	Cards            []Card       `json:"cards,omitempty"`
+	AttachmentLayout string       `json:"attachment_layout,omitempty"` // "carousel" or "list"
+	SuggestedActions []CardAction `json:"suggested_actions,omitempty"`
+}
+
+// newBotActivity creates a reply activity from the fields of a Bot Framework activity,
+// parsing its attachments and suggested actions.
+func newBotActivity(activityType, text string, attachments []interface{}, layout string, suggestedActions interface{}) ReplyActivity {
+	if activityType == "" {
+		activityType = ActivityMessage
+	}
+	return ReplyActivity{
+		Type:             activityType,
+		Text:             text,
+		Attachments:      attachments,
+		Cards:            ParseCards(attachments),
+		AttachmentLayout: layout,
+		SuggestedActions: ParseActions(suggestedActions),
+	}
+}
+
+// Actions returns every action the user can take on the activity: card buttons, suggested
+// actions and quick replies, the latter as imBack actions.
+func (a ReplyActivity) Actions() []CardAction {
+	var actions []CardAction
+	for _, card := range a.Cards {
+		actions = append(actions, card.Buttons...)
+	}
+	actions = append(actions, a.SuggestedActions...)
+	for _, q := range a.QuickReplies {
+		actions = append(actions, CardAction{Type: ActionIMBack, Title: q, Value: q})
+	}
+	return actions
+}
+
+// Reply is a bot's answer to a user message. A bot may answer with several activities;
+// they are kept in the order they were sent.
+type Reply struct {
+	Activities []ReplyActivity
+	Raw        []byte // Unparsed response body, kept for debugging
+}
+
+// TextReply creates a reply made of a single text message.
+func TextReply(text string, quickReplies []string) *Reply {
+	return &Reply{Activities: []ReplyActivity{{Type: ActivityMessage, Text: text, QuickReplies: quickReplies}}}
+}
+
+// Actions returns the actions offered by the reply's messages, in order.
+func (r *Reply) Actions() []CardAction {
+	var actions []CardAction
+	for _, a := range r.Messages() {
+		actions = append(actions, a.Actions()...)
+	}
+	return actions
+}
+
+// FindAction returns the offered action whose value or title matches choice, ignoring case
+// and surrounding spaces. Values are matched first.
+func (r *Reply) FindAction(choice string) (CardAction, bool) {
+	choice = strings.TrimSpace(choice)
+	if r == nil || choice == "" {
+		return CardAction{}, false
+	}
+	actions := r.Actions()
+	for _, a := range actions {
+		if v := a.ValueString(); v != "" && strings.EqualFold(v, choice) {
+			return a, true
+		}
+	}
+	for _, a := range actions {
+		if strings.EqualFold(strings.TrimSpace(a.Title), choice) {
+			return a, true
+		}
+	}
+	return CardAction{}, false
+}
+
+// Messages returns the message activities of the reply, skipping typing indicators and events.
+func (r *Reply) Messages() []ReplyActivity {
+	var messages []ReplyActivity
+	for _, a := range r.Activities {
+		if a.Type == ActivityMessage || a.Type == "" {
+			messages = append(messages, a)
+		}
+	}
+	return messages
+}
@@ -0,0 +1,237 @@
+package target
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultWebhookTemplate is the request body sent when a webhook target has no template.
+const DefaultWebhookTemplate = `{"message": "{{message}}", "conversation_id": "{{conversation_id}}"}`
+
+// WebhookConfig describes a bot reachable over a plain HTTP/JSON endpoint. It is stored as
+// the project's target_config.
+type WebhookConfig struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method,omitempty"`  // Defaults to POST
+	Headers map[string]string `json:"headers,omitempty"` // Extra headers; values are expanded like AuthValue
+	// AuthHeader and AuthValue set an authentication header, e.g. "Authorization" and
+	// "Bearer ${WEBHOOK_BOT_TOKEN}". ${VAR} references are read from the environment so
+	// secrets don't have to be stored with the project; only WEBHOOK_* variables are allowed.
+	AuthHeader string `json:"auth_header,omitempty"`
+	AuthValue  string `json:"auth_value,omitempty"`
+	// RequestTemplate is the JSON body. {{message}} and {{conversation_id}} are replaced with
+	// the JSON-escaped values, so they belong inside quotes.
+	RequestTemplate string `json:"request_template,omitempty"`
+	// ReplyPath selects the reply text, e.g. "$.messages[*].text". Each match becomes a
+	// message activity of its own. Defaults to "$.text".
+	ReplyPath string `json:"reply_path,omitempty"`
+	// QuickRepliesPath optionally selects the offered quick replies, e.g. "$.buttons[*].title".
+	QuickRepliesPath string `json:"quick_replies_path,omitempty"`
+	TimeoutSeconds   int    `json:"timeout_seconds,omitempty"` // Defaults to 15
+}
+
+// Webhook is a Target that posts each user message to a configurable HTTP endpoint.
+type Webhook struct {
+	cfg        WebhookConfig
+	httpClient *http.Client
+}
+
+// NewWebhook validates cfg and creates the target.
+func NewWebhook(cfg WebhookConfig) (*Webhook, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("webhook target: url is required")
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodPost
+	}
+	if cfg.RequestTemplate == "" {
+		cfg.RequestTemplate = DefaultWebhookTemplate
+	}
+	if cfg.ReplyPath == "" {
+		cfg.ReplyPath = "$.text"
+	}
+	for _, k := range mapKeys(cfg.Headers) {
+		if _, err := expandWebhookEnv(cfg.Headers[k]); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := expandWebhookEnv(cfg.AuthValue); err != nil {
+		return nil, err
+	}
+	for _, p := range []string{cfg.ReplyPath, cfg.QuickRepliesPath} {
+		if p == "" {
+			continue
+		}
+		if _, err := extractPath(nil, p); err != nil {
+			return nil, fmt.Errorf("webhook target: %w", err)
+		}
+	}
+	timeout := 15 * time.Second
+	if cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	return &Webhook{cfg: cfg, httpClient: &http.Client{Timeout: timeout}}, nil
+}
+
+// ParseWebhookConfig decodes a project's target_config.
+func ParseWebhookConfig(raw []byte) (WebhookConfig, error) {
+	var cfg WebhookConfig
+	if len(raw) == 0 {
+		return cfg, fmt.Errorf("webhook target: target_config is required")
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("webhook target: invalid target_config: %w", err)
+	}
+	return cfg, nil
+}
+
+func (h *Webhook) StartConversation(ctx context.Context) (string, error) {
+	return uuid.New().String(), nil
+}
+
+func (h *Webhook) SendMessage(ctx context.Context, conversationID, text string) (*Reply, error) {
+	body := renderTemplate(h.cfg.RequestTemplate, map[string]string{
+		"message":         strings.TrimSpace(text),
+		"conversation_id": conversationID,
+	})
+	req, err := http.NewRequestWithContext(ctx, h.cfg.Method, h.cfg.URL, strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range h.cfg.Headers {
+		value, err := expandWebhookEnv(v)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(k, value)
+	}
+	if h.cfg.AuthHeader != "" {
+		value, err := expandWebhookEnv(h.cfg.AuthValue)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(h.cfg.AuthHeader, value)
+	}
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("received non-2xx response: %d\nResponse body: %s", resp.StatusCode, string(raw))
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w\nResponse body: %s", err, string(raw))
+	}
+
+	// Every reply_path match is a separate message; quick replies belong to the last one
+	reply := &Reply{Raw: raw}
+	texts, err := extractPath(doc, h.cfg.ReplyPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, text := range stringValues(texts) {
+		reply.Activities = append(reply.Activities, ReplyActivity{Type: ActivityMessage, Text: text})
+	}
+	if h.cfg.QuickRepliesPath != "" {
+		choices, err := extractPath(doc, h.cfg.QuickRepliesPath)
+		if err != nil {
+			return nil, err
+		}
+		if quickReplies := stringValues(choices); len(quickReplies) > 0 {
+			if len(reply.Activities) == 0 {
+				reply.Activities = append(reply.Activities, ReplyActivity{Type: ActivityMessage})
+			}
+			reply.Activities[len(reply.Activities)-1].QuickReplies = quickReplies
+		}
+	}
+	return reply, nil
+}
+
+// EndConversation is a no-op: the webhook protocol has no notion of ending a conversation.
+func (h *Webhook) EndConversation(ctx context.Context, conversationID string) error {
+	return nil
+}
+
+// renderTemplate replaces {{name}} placeholders with the JSON-escaped values in a single
+// pass, so placeholders inside the values are sent as typed.
+func renderTemplate(tmpl string, values map[string]string) string {
+	names := mapKeys(values)
+	pairs := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		escaped, _ := json.Marshal(values[name])
+		pairs = append(pairs, "{{"+name+"}}", string(escaped[1:len(escaped)-1]))
+	}
+	return strings.NewReplacer(pairs...).Replace(tmpl)
+}
+
+// webhookEnvPrefix is the prefix of the environment variables a webhook target may read.
+// Projects can be edited through the API, so other server variables, such as the LLM API
+// keys, must never be sent to a webhook URL.
+const webhookEnvPrefix = "WEBHOOK_"
+
+// expandWebhookEnv replaces ${VAR} references with WEBHOOK_* environment variables and
+// rejects references to any other variable.
+func expandWebhookEnv(value string) (string, error) {
+	var denied string
+	expanded := os.Expand(value, func(name string) string {
+		if !strings.HasPrefix(name, webhookEnvPrefix) {
+			if denied == "" {
+				denied = name
+			}
+			return ""
+		}
+		return os.Getenv(name)
+	})
+	if denied != "" {
+		return "", fmt.Errorf("webhook target: ${%s} is not allowed, only %s* variables can be referenced", denied, webhookEnvPrefix)
+	}
+	return expanded, nil
+}
+
+// mapKeys returns the keys of m, sorted.
+func mapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// stringValues converts extracted values to strings, encoding non-string values as JSON.
+func stringValues(values []interface{}) []string {
+	var out []string
+	for _, v := range values {
+		switch s := v.(type) {
+		case nil:
+			continue
+		case string:
+			if s != "" {
+				out = append(out, s)
+			}
+		default:
+			b, _ := json.Marshal(s)
+			out = append(out, string(b))
+		}
+	}
+	return out
+}
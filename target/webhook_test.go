@@ -0,0 +1,113 @@
+package target
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWebhookEnvAllowlist(t *testing.T) {
+	t.Setenv("WEBHOOK_BOT_TOKEN", "bot-token")
+	t.Setenv("OPENAI_API_KEY", "server-secret")
+
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		io.WriteString(w, `{"text": "ok"}`)
+	}))
+	defer srv.Close()
+
+	bot, err := NewWebhook(WebhookConfig{
+		URL:        srv.URL,
+		AuthHeader: "Authorization",
+		AuthValue:  "Bearer ${WEBHOOK_BOT_TOKEN}",
+		Headers:    map[string]string{"X-Price": "10 USD"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bot.SendMessage(context.Background(), "c1", "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if auth := got.Get("Authorization"); auth != "Bearer bot-token" {
+		t.Errorf("Authorization = %q, want the WEBHOOK_ variable", auth)
+	}
+	if price := got.Get("X-Price"); price != "10 USD" {
+		t.Errorf("X-Price = %q, want it unchanged", price)
+	}
+
+	for _, cfg := range []WebhookConfig{
+		{URL: srv.URL, AuthHeader: "Authorization", AuthValue: "Bearer ${OPENAI_API_KEY}"},
+		{URL: srv.URL, Headers: map[string]string{"X-Key": "$OPENAI_API_KEY"}},
+		{URL: srv.URL, Headers: map[string]string{"X-Key": "${WEBHOOK_BOT_TOKEN}${HOME}"}},
+	} {
+		if _, err := NewWebhook(cfg); err == nil {
+			t.Errorf("NewWebhook(%+v) accepted a non-WEBHOOK_ variable", cfg)
+		}
+	}
+}
+
+func TestWebhookSendMessage(t *testing.T) {
+	var body map[string]string
+	response := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if response == "" {
+			http.Error(w, "bot down", http.StatusBadGateway)
+			return
+		}
+		io.WriteString(w, response)
+	}))
+	defer srv.Close()
+
+	bot, err := NewWebhook(WebhookConfig{
+		URL:              srv.URL,
+		RequestTemplate:  `{"session": "{{conversation_id}}", "input": "{{message}}"}`,
+		ReplyPath:        "$.messages[*].text",
+		QuickRepliesPath: "$.buttons[*].title",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Placeholders and quotes typed by the user are sent as typed
+	response = `{"messages": [{"text": "first"}, {"text": ""}, {"text": "second"}], "buttons": [{"title": "Yes"}, {"title": "No"}]}`
+	reply, err := bot.SendMessage(context.Background(), "conv-1", `  say "{{conversation_id}}" {{message}} `)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"session": "conv-1", "input": `say "{{conversation_id}}" {{message}}`}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("request body = %v, want %v", body, want)
+	}
+	wantActivities := []ReplyActivity{
+		{Type: ActivityMessage, Text: "first"},
+		{Type: ActivityMessage, Text: "second", QuickReplies: []string{"Yes", "No"}},
+	}
+	if !reflect.DeepEqual(reply.Activities, wantActivities) {
+		t.Errorf("activities = %+v, want %+v", reply.Activities, wantActivities)
+	}
+
+	// Quick replies without text still reach the simulator
+	response = `{"buttons": [{"title": "Retry"}]}`
+	if reply, err = bot.SendMessage(context.Background(), "conv-1", "hi"); err != nil {
+		t.Fatal(err)
+	}
+	wantActivities = []ReplyActivity{{Type: ActivityMessage, QuickReplies: []string{"Retry"}}}
+	if !reflect.DeepEqual(reply.Activities, wantActivities) {
+		t.Errorf("activities = %+v, want %+v", reply.Activities, wantActivities)
+	}
+
+	response = ""
+	if _, err := bot.SendMessage(context.Background(), "conv-1", "hi"); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("SendMessage() error = %v, want the bot's status", err)
+	}
+}